@@ -0,0 +1,283 @@
+package guc
+
+import (
+	"iter"
+	"sync/atomic"
+)
+
+// AbstractCollection implements Collection in terms of Iterator, Size and Add,
+// so a custom collection only needs these three methods:
+//
+//	type MyCollection struct {
+//		guc.AbstractCollection
+//		...
+//	}
+//
+//	func NewMyCollection() *MyCollection {
+//		c := &MyCollection{}
+//		c.InitAbstractCollection(c)
+//		return c
+//	}
+//
+// Remove, RemoveIf, RetainAll and Clear need Iterator().Remove().
+// any method may be overridden, the derived methods always call the
+// overriding one through the embedding collection
+type AbstractCollection struct {
+	self Collection
+	// nil means DefaultEquality
+	equality Equality
+	// identity hash code, assigned on first use
+	// Volatile
+	id int64
+}
+
+var collectionIds int64
+
+// InitAbstractCollection binds the helper to the embedding collection,
+// it must be called before any other method
+func (this *AbstractCollection) InitAbstractCollection(self Collection) {
+	this.self = self
+}
+
+// SetEquality sets the strategy used by Contains and Remove,
+// nil means DefaultEquality
+func (this *AbstractCollection) SetEquality(equality Equality) {
+	this.equality = equality
+}
+
+func (this *AbstractCollection) ForEach(consumer func(i interface{})) {
+	iter := this.self.Iterator()
+	for iter.HasNext() {
+		consumer(iter.Next())
+	}
+}
+
+func (this *AbstractCollection) All() iter.Seq[any] {
+	return func(yield func(any) bool) {
+		iter := this.self.Iterator()
+		for iter.HasNext() {
+			if !yield(iter.Next()) {
+				return
+			}
+		}
+	}
+}
+
+func (this *AbstractCollection) IsEmpty() bool {
+	return this.self.Size() == 0
+}
+
+func (this *AbstractCollection) Contains(i interface{}) bool {
+	r, _ := this.TryContains(i)
+	return r
+}
+
+// TryContains is like Contains, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *AbstractCollection) TryContains(i interface{}) (bool, error) {
+	return this.find(this.self.Iterator(), i)
+}
+
+// find advances iter past an element equal to i, or returns false and the
+// first error of the equality. incomparable elements are skipped
+func (this *AbstractCollection) find(iter Iterator, i interface{}) (bool, error) {
+	var firstErr error
+	for iter.HasNext() {
+		r, err := tryEqual(this.equality, iter.Next(), i)
+		if r && err == nil {
+			return true, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return false, firstErr
+}
+
+func (this *AbstractCollection) ToArray() []interface{} {
+	result := make([]interface{}, 0, this.self.Size())
+	iter := this.self.Iterator()
+	for iter.HasNext() {
+		result = append(result, iter.Next())
+	}
+	return result
+}
+
+func (this *AbstractCollection) FillArray(arr []interface{}) []interface{} {
+	data := this.self.ToArray()
+	if len(arr) >= len(data) {
+		copy(arr, data)
+		return arr[:len(data)]
+	} else {
+		return data
+	}
+}
+
+func (this *AbstractCollection) Remove(i interface{}) bool {
+	r, _ := this.TryRemove(i)
+	return r
+}
+
+// TryRemove is like Remove, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *AbstractCollection) TryRemove(i interface{}) (bool, error) {
+	iter := this.self.Iterator()
+	r, err := this.find(iter, i)
+	if r {
+		iter.Remove()
+	}
+	return r, err
+}
+
+func (this *AbstractCollection) ContainsAll(coll Collection) bool {
+	iter := coll.Iterator()
+	for iter.HasNext() {
+		if !this.self.Contains(iter.Next()) {
+			return false
+		}
+	}
+	return true
+}
+
+func (this *AbstractCollection) AddAll(coll Collection) bool {
+	changed := false
+	iter := coll.Iterator()
+	for iter.HasNext() {
+		if this.self.Add(iter.Next()) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (this *AbstractCollection) RemoveAll(coll Collection) bool {
+	removed := false
+	iter := coll.Iterator()
+	for iter.HasNext() {
+		if this.self.Remove(iter.Next()) {
+			removed = true
+		}
+	}
+	return removed
+}
+
+func (this *AbstractCollection) RemoveIf(predicate func(i interface{}) bool) bool {
+	iter := this.self.Iterator()
+	for iter.HasNext() {
+		if predicate(iter.Next()) {
+			iter.Remove()
+			return true
+		}
+	}
+	return false
+}
+
+func (this *AbstractCollection) RetainAll(coll Collection) bool {
+	changed := false
+	iter := this.self.Iterator()
+	for iter.HasNext() {
+		if !coll.Contains(iter.Next()) {
+			iter.Remove()
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (this *AbstractCollection) Clear() {
+	iter := this.self.Iterator()
+	for iter.HasNext() {
+		iter.Next()
+		iter.Remove()
+	}
+}
+
+// Equals is identity, embedding lists, sets and queues should override it
+// following the rules of Collection
+func (this *AbstractCollection) Equals(i interface{}) bool {
+	c, ok := i.(Collection)
+	return ok && c == this.self
+}
+
+// HashCode is an identity hash code, which doesn't depend on the address
+func (this *AbstractCollection) HashCode() int {
+	id := atomic.LoadInt64(&this.id)
+	if id == 0 {
+		atomic.CompareAndSwapInt64(&this.id, 0, atomic.AddInt64(&collectionIds, 1))
+		id = atomic.LoadInt64(&this.id)
+	}
+	return int(id)
+}
+
+// AbstractQueue implements Queue in terms of Iterator, Size, Offer, Poll
+// and Peek, see AbstractCollection. Add is Offer, the panicking methods are
+// derived from Poll and Peek, so nil can't be used as element
+type AbstractQueue struct {
+	AbstractCollection
+	selfQueue Queue
+}
+
+// InitAbstractQueue binds the helper to the embedding queue,
+// it must be called before any other method
+func (this *AbstractQueue) InitAbstractQueue(self Queue) {
+	this.InitAbstractCollection(self)
+	this.selfQueue = self
+}
+
+func (this *AbstractQueue) Add(i interface{}) bool {
+	return this.selfQueue.Offer(i)
+}
+
+// RemoveHead panics if the queue is empty, see TryRemoveHead
+func (this *AbstractQueue) RemoveHead() interface{} {
+	i, err := this.TryRemoveHead()
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// TryRemoveHead retrieves and removes the head, return ErrEmpty if the queue is empty
+func (this *AbstractQueue) TryRemoveHead() (interface{}, error) {
+	i := this.selfQueue.Poll()
+	if i == nil {
+		return nil, ErrEmpty
+	}
+	return i, nil
+}
+
+// Element panics if the queue is empty, see TryElement
+func (this *AbstractQueue) Element() interface{} {
+	i, err := this.TryElement()
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// TryElement retrieves the head, return ErrEmpty if the queue is empty
+func (this *AbstractQueue) TryElement() (interface{}, error) {
+	i := this.selfQueue.Peek()
+	if i == nil {
+		return nil, ErrEmpty
+	}
+	return i, nil
+}
+
+// Equals follows the rules for queues, see Collection
+func (this *AbstractQueue) Equals(i interface{}) bool {
+	q, ok := i.(Queue)
+	if !ok {
+		return false
+	}
+	return q == this.selfQueue || QueueEquals(this.selfQueue, q)
+}
+
+func (this *AbstractQueue) HashCode() int {
+	return QueueHashCode(this.selfQueue)
+}
+
+func (this *AbstractQueue) Clear() {
+	for this.selfQueue.Poll() != nil {
+	}
+}
@@ -0,0 +1,237 @@
+package guc
+
+import (
+	"testing"
+)
+
+// sampleBag only implements Iterator, Size and Add
+type sampleBag struct {
+	AbstractCollection
+	data []interface{}
+}
+
+func newSampleBag(values ...interface{}) *sampleBag {
+	b := &sampleBag{}
+	b.InitAbstractCollection(b)
+	for _, v := range values {
+		b.Add(v)
+	}
+	return b
+}
+
+type sampleBagIter struct {
+	bag     *sampleBag
+	idx     int
+	lastRet int
+}
+
+func (this *sampleBagIter) HasNext() bool {
+	return this.idx < len(this.bag.data)
+}
+
+func (this *sampleBagIter) Next() interface{} {
+	this.lastRet = this.idx
+	this.idx++
+	return this.bag.data[this.lastRet]
+}
+
+func (this *sampleBagIter) Remove() {
+	if this.lastRet < 0 {
+		panic("illegal state")
+	}
+	this.bag.data = append(this.bag.data[:this.lastRet], this.bag.data[this.lastRet+1:]...)
+	this.idx = this.lastRet
+	this.lastRet = -1
+}
+
+func (this *sampleBagIter) ForEachRemaining(consumer func(i interface{})) {
+	for this.HasNext() {
+		consumer(this.Next())
+	}
+}
+
+func (this *sampleBag) Iterator() Iterator {
+	return &sampleBagIter{bag: this, lastRet: -1}
+}
+
+func (this *sampleBag) Size() int {
+	return len(this.data)
+}
+
+func (this *sampleBag) Add(i interface{}) bool {
+	this.data = append(this.data, i)
+	return true
+}
+
+// sampleFifo only implements Iterator, Size, Offer, Poll and Peek
+type sampleFifo struct {
+	AbstractQueue
+	bag *sampleBag
+}
+
+func newSampleFifo() *sampleFifo {
+	q := &sampleFifo{bag: newSampleBag()}
+	q.InitAbstractQueue(q)
+	return q
+}
+
+func (this *sampleFifo) Iterator() Iterator {
+	return this.bag.Iterator()
+}
+
+func (this *sampleFifo) Size() int {
+	return this.bag.Size()
+}
+
+func (this *sampleFifo) Offer(i interface{}) bool {
+	return this.bag.Add(i)
+}
+
+func (this *sampleFifo) Poll() interface{} {
+	if len(this.bag.data) == 0 {
+		return nil
+	}
+	i := this.bag.data[0]
+	this.bag.data = this.bag.data[1:]
+	return i
+}
+
+func (this *sampleFifo) Peek() interface{} {
+	if len(this.bag.data) == 0 {
+		return nil
+	}
+	return this.bag.data[0]
+}
+
+func TestAbstractCollection(t *testing.T) {
+	var _ Collection = newSampleBag()
+	b := newSampleBag(1, 2, 3, 2)
+	if b.IsEmpty() || b.Size() != 4 {
+		t.Fatal("bag size should be 4")
+	}
+	if !b.Contains(2) || b.Contains(5) {
+		t.Fatal("contains result error")
+	}
+	if !b.ContainsAll(newSampleBag(1, 3)) || b.ContainsAll(newSampleBag(1, 5)) {
+		t.Fatal("contains all result error")
+	}
+	if !b.Remove(2) || b.Size() != 3 || !b.Contains(2) {
+		t.Fatal("remove should only remove the first match")
+	}
+	if !b.AddAll(newSampleBag(4, 5)) || b.Size() != 5 {
+		t.Fatal("add all error")
+	}
+	if !b.RemoveAll(newSampleBag(1, 5, 9)) || b.Size() != 3 {
+		t.Fatal("remove all error")
+	}
+	if !b.RetainAll(newSampleBag(2, 3)) || b.Size() != 2 {
+		t.Fatal("retain all error")
+	}
+	if b.RetainAll(newSampleBag(2, 3)) {
+		t.Fatal("retain all should return false if nothing changed")
+	}
+	arr := b.ToArray()
+	if len(arr) != 2 || arr[0] != 3 || arr[1] != 2 {
+		t.Fatal("to array error", arr)
+	}
+	if len(b.FillArray(make([]interface{}, 1))) != 2 || len(b.FillArray(make([]interface{}, 4))) != 2 {
+		t.Fatal("fill array error")
+	}
+	sum := 0
+	b.ForEach(func(i interface{}) {
+		sum += i.(int)
+	})
+	for v := range b.All() {
+		sum += v.(int)
+	}
+	if sum != 10 {
+		t.Fatal("for each and all should visit all elements")
+	}
+	if !b.RemoveIf(func(i interface{}) bool { return i.(int) > 0 }) || b.Size() != 1 {
+		t.Fatal("remove if should remove the first match")
+	}
+	b.Clear()
+	if !b.IsEmpty() {
+		t.Fatal("bag should be empty")
+	}
+	if !b.Equals(b) || b.Equals(newSampleBag()) {
+		t.Fatal("equals error")
+	}
+}
+
+type sampleCountingBag struct {
+	*sampleBag
+	contains int
+}
+
+func (this *sampleCountingBag) Contains(i interface{}) bool {
+	this.contains++
+	return this.sampleBag.AbstractCollection.Contains(i)
+}
+
+func TestAbstractCollection_Override(t *testing.T) {
+	b := &sampleCountingBag{sampleBag: newSampleBag(1, 2)}
+	b.InitAbstractCollection(b)
+	if !b.ContainsAll(newSampleBag(1, 2)) || b.contains != 2 {
+		t.Fatal("derived methods should call the overriding method")
+	}
+}
+
+func TestAbstractCollection_Equality(t *testing.T) {
+	b := newSampleBag("a", "B")
+	b.SetEquality(EqualityFunc(func(o1, o2 interface{}) (bool, error) {
+		return o1.(string) == o2.(string) || o1.(string) == "B" && o2.(string) == "b", nil
+	}))
+	if !b.Contains("b") || !b.Remove("b") || b.Size() != 1 {
+		t.Fatal("contains and remove should use the equality")
+	}
+}
+
+func TestAbstractQueue(t *testing.T) {
+	var _ Queue = newSampleFifo()
+	q := newSampleFifo()
+	if _, err := q.TryRemoveHead(); err != ErrEmpty {
+		t.Fatal("try remove head of an empty queue should return ErrEmpty")
+	}
+	if _, err := q.TryElement(); err != ErrEmpty {
+		t.Fatal("try element of an empty queue should return ErrEmpty")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		q.Element()
+		return
+	}()
+	if !r {
+		t.Fatal("should panic when Element of an empty queue")
+	}
+	q.Add(1)
+	q.AddAll(newSampleBag(2, 3))
+	if q.Element() != 1 || q.RemoveHead() != 1 || q.Size() != 2 {
+		t.Fatal("queue should be fifo")
+	}
+	if !q.Contains(3) || !q.Remove(3) || q.Size() != 1 {
+		t.Fatal("contains and remove error")
+	}
+	q.Clear()
+	if !q.IsEmpty() || q.Poll() != nil {
+		t.Fatal("queue should be empty")
+	}
+}
+
+func TestAbstractCollection_HashCode(t *testing.T) {
+	b1, b2 := newSampleBag(1), newSampleBag(1)
+	if b1.HashCode() != b1.HashCode() || b1.HashCode() == b2.HashCode() {
+		t.Fatal("hashcode should be an identity hashcode")
+	}
+	q1, q2 := newSampleFifo(), newSampleFifo()
+	q1.AddAll(newSampleBag(1, 2))
+	q2.AddAll(newSampleBag(2, 1))
+	if !q1.Equals(q2) || q1.HashCode() != q2.HashCode() {
+		t.Fatal("queues with the same elements should be equal")
+	}
+	if q1.Equals(b1) {
+		t.Fatal("queue should not be equal to a collection which isn't a queue")
+	}
+}
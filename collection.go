@@ -1,6 +1,9 @@
 package guc
 
-import "time"
+import (
+	"iter"
+	"time"
+)
 
 // this interface imposes basic operations on objects
 type Object interface {
@@ -12,11 +15,26 @@ type Comparable interface {
 	CompareTo(i interface{}) int
 }
 
+// this interface is used by hash based collections, objects which are
+// equal must return the same hash code
+type Hashable interface {
+	Object
+	HashCode() int
+}
+
+// this interface decides whether two elements of a collection are equal,
+// it returns an error instead of panicking if they can't be compared
+type Equality interface {
+	Equal(o1, o2 interface{}) (bool, error)
+}
+
 // this interface represents the function that compares two objects
 type Comparator interface {
 	Compare(o1, o2 interface{}) int
 }
 
+// HasNext doesn't move the iterator, Next returns the next element and
+// advances, Remove deletes the element returned by the last Next
 type Iterator interface {
 	HasNext() bool
 	Next() interface{}
@@ -27,8 +45,20 @@ type Iterator interface {
 type Iterable interface {
 	Iterator() Iterator
 	ForEach(consumer func(i interface{}))
+	// for range-over-func loops
+	All() iter.Seq[any]
 }
 
+// Equals and HashCode of collections have value semantics: elements are
+// compared with DefaultEquality and hashed with Hash, so equal collections
+// have the same hash code.
+//   - lists are equal to lists holding equal elements in the same order,
+//     see ListEquals
+//   - sets are equal to sets holding equal elements, see SetEquals
+//   - queues are equal to queues holding equal elements with the same
+//     multiplicity in any order, since the iteration order of a queue depends
+//     on its implementation, see QueueEquals
+//   - other collections are only equal to themselves
 type Collection interface {
 	Iterable
 	Size() int
@@ -42,6 +72,7 @@ type Collection interface {
 	ContainsAll(coll Collection) bool
 	AddAll(coll Collection) bool
 	RemoveAll(coll Collection) bool
+	// RemoveIf removes the first element matching predicate
 	RemoveIf(predicate func(i interface{}) bool) bool
 	RetainAll(coll Collection) bool
 	Clear()
@@ -49,6 +80,56 @@ type Collection interface {
 	HashCode() int
 }
 
+// ListIterator traverses a list in both directions
+type ListIterator interface {
+	Iterator
+	HasPrevious() bool
+	Previous() interface{}
+	// index of the element returned by a subsequent Next
+	NextIndex() int
+	// index of the element returned by a subsequent Previous
+	PreviousIndex() int
+	// Set replaces the element returned by the last Next or Previous
+	Set(i interface{})
+	// Add inserts i before the element returned by a subsequent Next
+	Add(i interface{})
+}
+
+// List is an ordered collection, indexes start at 0 and methods panic
+// with ErrIndexOutOfRange if an index is out of range
+type List interface {
+	Collection
+
+	Get(index int) interface{}
+	// Set replaces the element at index, returns the old one
+	Set(index int, i interface{}) interface{}
+	// IndexOf returns the index of the first occurrence of i, -1 if none
+	IndexOf(i interface{}) int
+	// LastIndexOf returns the index of the last occurrence of i, -1 if none
+	LastIndexOf(i interface{}) int
+	// AddAt inserts i at index, shifting the following elements
+	AddAt(index int, i interface{})
+	// RemoveAt removes the element at index, returns it
+	RemoveAt(index int) interface{}
+	// SubList returns the elements in [from, to), implementations
+	// document whether it is a view or a copy
+	SubList(from, to int) List
+	ListIterator() ListIterator
+}
+
+// Set is a collection without equal elements, Add returns false if
+// an equal element is already present
+type Set interface {
+	Collection
+
+	// Union returns a new set holding the elements of both sets
+	Union(s Set) Set
+	// Intersect returns a new set holding the elements contained in both sets
+	Intersect(s Set) Set
+	// Difference returns a new set holding the elements not contained in s
+	Difference(s Set) Set
+}
+
 type Queue interface {
 	Collection
 
@@ -87,3 +168,105 @@ type BlockingQueue interface {
 	DrainTo(coll Collection) int
 	DrainToWithLimit(coll Collection, max int) int
 }
+
+// Hash returns the hash code of i, consistent with DefaultEquality:
+// HashCode for Hashable values, the map hash for other comparable values,
+// 0 for nil, Objects which aren't Hashable and values which aren't comparable
+func Hash(i interface{}) int {
+	if i == nil {
+		return 0
+	}
+	if h, ok := i.(Hashable); ok {
+		return h.HashCode()
+	}
+	if _, ok := i.(Object); ok {
+		return 0
+	}
+	h, ok := hash(i)
+	if !ok {
+		return 0
+	}
+	return int(h)
+}
+
+// ListEquals reports whether l1 and l2 hold equal elements in the same order
+func ListEquals(l1, l2 Collection) bool {
+	a1, a2 := l1.ToArray(), l2.ToArray()
+	if len(a1) != len(a2) {
+		return false
+	}
+	for idx := range a1 {
+		if !equal(nil, a1[idx], a2[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListHashCode combines the element hashes in iteration order
+func ListHashCode(l Collection) int {
+	h := 1
+	for _, v := range l.ToArray() {
+		h = 31*h + Hash(v)
+	}
+	return h
+}
+
+// SetEquals reports whether s1 and s2 hold equal elements
+func SetEquals(s1, s2 Collection) bool {
+	a2 := s2.ToArray()
+	if s1.Size() != len(a2) {
+		return false
+	}
+	for _, v := range a2 {
+		if !s1.Contains(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// SetHashCode is the sum of the element hashes
+func SetHashCode(s Collection) int {
+	h := 0
+	for _, v := range s.ToArray() {
+		h += Hash(v)
+	}
+	return h
+}
+
+// QueueEquals reports whether q1 and q2 hold equal elements with the same
+// multiplicity, regardless of their order
+func QueueEquals(q1, q2 Collection) bool {
+	a1, a2 := q1.ToArray(), q2.ToArray()
+	if len(a1) != len(a2) {
+		return false
+	}
+	buckets := make(map[int][]interface{}, len(a1))
+	for _, v := range a1 {
+		h := Hash(v)
+		buckets[h] = append(buckets[h], v)
+	}
+	for _, v := range a2 {
+		h := Hash(v)
+		bucket := buckets[h]
+		found := false
+		for idx, e := range bucket {
+			if equal(nil, e, v) {
+				bucket[idx] = bucket[len(bucket)-1]
+				buckets[h] = bucket[:len(bucket)-1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// QueueHashCode is the sum of the element hashes
+func QueueHashCode(q Collection) int {
+	return SetHashCode(q)
+}
@@ -0,0 +1,60 @@
+package guc
+
+import (
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	if Hash(nil) != 0 || Hash([]int{1}) != 0 {
+		t.Fatal("hash of nil and of incomparable values should be 0")
+	}
+	if Hash(keyObject2{i: 1}) != Hash(keyObject2{i: 1}) || Hash("a") != Hash("a") {
+		t.Fatal("equal values must have the same hash")
+	}
+	if Hash(&hashableKey{id: 7}) != 7 {
+		t.Fatal("hash of hashable values should be their hash code")
+	}
+	if Hash(newSampleItem(1)) != 0 {
+		t.Fatal("hash of objects which aren't hashable should be 0")
+	}
+}
+
+func TestListEquals(t *testing.T) {
+	if !ListEquals(newSampleBag(1, 2, 2), newSampleBag(1, 2, 2)) {
+		t.Fatal("lists with the same elements in the same order should be equal")
+	}
+	if ListEquals(newSampleBag(1, 2, 2), newSampleBag(2, 1, 2)) || ListEquals(newSampleBag(1), newSampleBag(1, 1)) {
+		t.Fatal("lists in another order should not be equal")
+	}
+	if ListHashCode(newSampleBag(1, 2)) != ListHashCode(newSampleBag(1, 2)) ||
+		ListHashCode(newSampleBag(1, 2)) == ListHashCode(newSampleBag(2, 1)) {
+		t.Fatal("list hashcode should depend on the order")
+	}
+}
+
+func TestSetEquals(t *testing.T) {
+	if !SetEquals(newSampleBag(1, 2, 3), newSampleBag(3, 1, 2)) {
+		t.Fatal("sets with the same elements should be equal")
+	}
+	if SetEquals(newSampleBag(1, 2, 3), newSampleBag(1, 2, 4)) || SetEquals(newSampleBag(1, 2), newSampleBag(1, 2, 3)) {
+		t.Fatal("sets with other elements should not be equal")
+	}
+	if SetHashCode(newSampleBag(1, 2, 3)) != SetHashCode(newSampleBag(3, 2, 1)) {
+		t.Fatal("equal sets must have the same hashcode")
+	}
+}
+
+func TestQueueEquals(t *testing.T) {
+	if !QueueEquals(newSampleBag(1, 2, 2, newSampleItem(5)), newSampleBag(2, newSampleItem(5), 1, 2)) {
+		t.Fatal("queues with the same elements should be equal")
+	}
+	if QueueEquals(newSampleBag(1, 2, 2), newSampleBag(1, 1, 2)) {
+		t.Fatal("queues with other multiplicities should not be equal")
+	}
+	if QueueEquals(newSampleBag([]int{1}), newSampleBag([]int{1})) {
+		t.Fatal("incomparable elements should not be equal")
+	}
+	if QueueHashCode(newSampleBag(1, 2, 2)) != QueueHashCode(newSampleBag(2, 1, 2)) {
+		t.Fatal("equal queues must have the same hashcode")
+	}
+}
@@ -0,0 +1,111 @@
+package guc
+
+import (
+	"cmp"
+	"reflect"
+)
+
+// ComparatorFunc is an ordinary function used as Comparator
+type ComparatorFunc func(o1, o2 interface{}) int
+
+func (f ComparatorFunc) Compare(o1, o2 interface{}) int {
+	return f(o1, o2)
+}
+
+// NaturalOrder compares Comparable values by CompareTo, and values of Go
+// ordered types (integers, floats, strings) by their natural order.
+// both values must be of the same type
+func NaturalOrder() Comparator {
+	return ComparatorFunc(naturalCompare)
+}
+
+func naturalCompare(o1, o2 interface{}) int {
+	if v, ok := o1.(Comparable); ok {
+		return v.CompareTo(o2)
+	}
+	// by kind, so named types like time.Duration are ordered too
+	v1, v2 := reflect.ValueOf(o1), reflect.ValueOf(o2)
+	if !v1.IsValid() || !v2.IsValid() || v1.Type() != v2.Type() {
+		panic("values of different types have no natural order")
+	}
+	switch v1.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(v1.Int(), v2.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(v1.Uint(), v2.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(v1.Float(), v2.Float())
+	case reflect.String:
+		return cmp.Compare(v1.String(), v2.String())
+	default:
+		panic("type has no natural order: " + v1.Type().String())
+	}
+}
+
+// Reversed imposes the reverse ordering of c
+func Reversed(c Comparator) Comparator {
+	return ComparatorFunc(func(o1, o2 interface{}) int {
+		return c.Compare(o2, o1)
+	})
+}
+
+// ThenComparing orders by c1 first, then by c2 for the values c1 considers equal
+func ThenComparing(c1, c2 Comparator) Comparator {
+	return ComparatorFunc(func(o1, o2 interface{}) int {
+		r := c1.Compare(o1, o2)
+		if r != 0 {
+			return r
+		}
+		return c2.Compare(o1, o2)
+	})
+}
+
+// Comparing orders values by the natural order of the keys extracted by keyFn
+func Comparing(keyFn func(i interface{}) interface{}) Comparator {
+	return ComparingWith(keyFn, NaturalOrder())
+}
+
+// ComparingWith orders values by comparing the keys extracted by keyFn with c
+func ComparingWith(keyFn func(i interface{}) interface{}, c Comparator) Comparator {
+	return ComparatorFunc(func(o1, o2 interface{}) int {
+		return c.Compare(keyFn(o1), keyFn(o2))
+	})
+}
+
+// NullsFirst considers nil, including nil pointers, less than non-nil values,
+// which are compared by c
+func NullsFirst(c Comparator) Comparator {
+	return nullsComparator(c, -1)
+}
+
+// NullsLast considers nil, including nil pointers, greater than non-nil values,
+// which are compared by c
+func NullsLast(c Comparator) Comparator {
+	return nullsComparator(c, 1)
+}
+
+func nullsComparator(c Comparator, nilOrder int) Comparator {
+	return ComparatorFunc(func(o1, o2 interface{}) int {
+		n1, n2 := isNil(o1), isNil(o2)
+		if n1 && n2 {
+			return 0
+		} else if n1 {
+			return nilOrder
+		} else if n2 {
+			return -nilOrder
+		}
+		return c.Compare(o1, o2)
+	})
+}
+
+func isNil(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+		return v.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package guc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNaturalOrder(t *testing.T) {
+	c := NaturalOrder()
+	if c.Compare(1, 2) >= 0 || c.Compare(2, 1) <= 0 || c.Compare(3, 3) != 0 {
+		t.Fatal("int natural order error")
+	}
+	if c.Compare("a", "b") >= 0 || c.Compare(1.5, 0.5) <= 0 || c.Compare(uint8(1), uint8(1)) != 0 {
+		t.Fatal("natural order error")
+	}
+	if c.Compare(newSampleItem(1), newSampleItem(2)) >= 0 {
+		t.Fatal("comparable natural order error")
+	}
+	type id int
+	if c.Compare(time.Second, time.Minute) >= 0 || c.Compare(id(2), id(1)) <= 0 {
+		t.Fatal("named types should follow the order of their kind")
+	}
+
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		c.Compare(struct{}{}, struct{}{})
+		return
+	}()
+	if !r {
+		t.Fatal("should panic on types without natural order")
+	}
+}
+
+func TestNewPriorityWithComparatorFunc(t *testing.T) {
+	p := NewPriorityWithComparator(Reversed(NaturalOrder()))
+	for _, v := range []int{3, 1, 2} {
+		p.Add(v)
+	}
+	for _, v := range []int{3, 2, 1} {
+		if p.Poll() != v {
+			t.Fatal("should poll in reversed order")
+		}
+	}
+
+	p = NewPriorityWithComparator(ComparatorFunc(func(o1, o2 interface{}) int {
+		return len(o1.(string)) - len(o2.(string))
+	}))
+	p.Add("ccc")
+	p.Add("a")
+	if p.Poll() != "a" {
+		t.Fatal("should poll the shortest string")
+	}
+}
+
+func TestThenComparing(t *testing.T) {
+	c := ThenComparing(
+		Comparing(func(i interface{}) interface{} { return i.(*sampleJob).priority }),
+		Reversed(ComparingWith(func(i interface{}) interface{} { return i.(*sampleJob).id }, NaturalOrder())))
+	j1 := &sampleJob{priority: 1, id: 1}
+	j2 := &sampleJob{priority: 1, id: 2}
+	j3 := &sampleJob{priority: 0, id: 3}
+	if c.Compare(j3, j1) >= 0 {
+		t.Fatal("should order by priority first")
+	}
+	if c.Compare(j2, j1) >= 0 {
+		t.Fatal("should order by reversed id for equal priority")
+	}
+	if c.Compare(j1, j1) != 0 {
+		t.Fatal("should be equal")
+	}
+}
+
+func TestNullsFirst(t *testing.T) {
+	var nilItem *sampleItem
+	first := NullsFirst(NaturalOrder())
+	if first.Compare(nil, 1) >= 0 || first.Compare(1, nil) <= 0 || first.Compare(nil, nil) != 0 {
+		t.Fatal("nil should be first")
+	}
+	if first.Compare(nilItem, newSampleItem(1)) >= 0 {
+		t.Fatal("nil pointer should be first")
+	}
+	last := NullsLast(NaturalOrder())
+	if last.Compare(nil, 1) <= 0 || last.Compare(1, nil) >= 0 || last.Compare(1, 2) >= 0 {
+		t.Fatal("nil should be last")
+	}
+}
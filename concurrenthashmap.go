@@ -2,6 +2,7 @@ package guc
 
 import (
 	"fmt"
+	"iter"
 	"math/bits"
 	"runtime"
 	"strconv"
@@ -142,7 +143,7 @@ func (en *baseNode) find(n *node, h int32, k interface{}) (*node, bool) {
 		for {
 			if h == e.hash {
 				ek := e.getKey()
-				if ek == k {
+				if keyEquals(k, ek) {
 					return e, true
 				}
 			}
@@ -191,8 +192,7 @@ func (en *forwardingNode) find(n *node, h int32, k interface{}) (*node, bool) {
 		} // end of if
 		for {
 			eh := e.hash
-			ek := e.key
-			if eh == h && ek == k {
+			if eh == h && keyEquals(k, e.getKey()) {
 				return e, true
 			}
 			if eh < 0 {
@@ -259,8 +259,28 @@ func tableSizeFor(c int32) int32 {
 	}
 }
 
-func hash(v interface{}) uintptr {
-	return Nilinterhash(unsafe.Pointer(&v), uintptr(hashSeed))
+// hash uses HashCode for Hashable keys, otherwise the runtime hash.
+// return false if the key is not hashable
+func hash(v interface{}) (h uintptr, ok bool) {
+	if hv, isHashable := v.(Hashable); isHashable {
+		return uintptr(hv.HashCode()), true
+	}
+	defer func() {
+		if recover() != nil {
+			h, ok = 0, false
+		}
+	}()
+	return Nilinterhash(unsafe.Pointer(&v), uintptr(hashSeed)), true
+}
+
+// keyEquals agrees with hash: Hashable keys are compared with Equals,
+// others with ==, even if they implement Object, since equal keys must
+// have the same hash. both keys must be hashable
+func keyEquals(k, ek interface{}) bool {
+	if o, ok := k.(Hashable); ok {
+		return o.Equals(ek)
+	}
+	return k == ek
 }
 
 func equals(v1, v2 *interface{}) bool {
@@ -280,9 +300,21 @@ func casTabAt(tab *[]unsafe.Pointer, i int32, c, v *node) bool {
 }
 
 func NewConcurrentHashMap(initialCapacity, concurrencyLevel int32) *ConcurrentHashMap {
+	cmap, err := NewConcurrentHashMapChecked(initialCapacity, concurrencyLevel)
+	if err != nil {
+		panic(err)
+	}
+	return cmap
+}
+
+// NewConcurrentHashMapChecked returns ErrIllegalArgument instead of
+// panicking if initialCapacity is negative
+func NewConcurrentHashMapChecked(initialCapacity, concurrencyLevel int32) (*ConcurrentHashMap, error) {
 	cmap := ConcurrentHashMap{}
-	cmap.init(initialCapacity, concurrencyLevel)
-	return &cmap
+	if err := cmap.init(initialCapacity, concurrencyLevel); err != nil {
+		return nil, err
+	}
+	return &cmap, nil
 }
 
 func (m *ConcurrentHashMap) sumCount() int64 {
@@ -309,9 +341,9 @@ func (m *ConcurrentHashMap) getNextTable() *[]unsafe.Pointer {
 	return (*[]unsafe.Pointer)(atomic.LoadPointer(&m.nextTable))
 }
 
-func (m *ConcurrentHashMap) init(initialCapacity, concurrencyLevel int32) {
+func (m *ConcurrentHashMap) init(initialCapacity, concurrencyLevel int32) error {
 	if initialCapacity < 0 {
-		panic("initialCapacity should > 0")
+		return ErrIllegalArgument
 	}
 	var capacity int32 = 0
 	if initialCapacity < concurrencyLevel {
@@ -323,6 +355,7 @@ func (m *ConcurrentHashMap) init(initialCapacity, concurrencyLevel int32) {
 		capacity = tableSizeFor(initialCapacity + (initialCapacity >> 1) + 1)
 	}
 	m.sizeCtl = capacity
+	return nil
 }
 
 func (m *ConcurrentHashMap) initTable() *[]unsafe.Pointer {
@@ -370,7 +403,11 @@ func (m *ConcurrentHashMap) Load(key interface{}) (interface{}, bool) {
 	if key == nil {
 		panic("key is nil!")
 	}
-	h := spread(hash(key))
+	hc, ok := hash(key)
+	if !ok {
+		return nil, false
+	}
+	h := spread(hc)
 	tab := m.getTable()
 	// not initialized
 	if tab == nil {
@@ -389,11 +426,11 @@ func (m *ConcurrentHashMap) Load(key interface{}) (interface{}, bool) {
 	eh := e.hash
 	if h == eh {
 		ek := e.getKey()
-		if key == ek {
+		if keyEquals(key, ek) {
 			return e.getValue(), true
 		}
 	} else if eh < 0 {
-		p, ok := e.extern.find(e, h, &key)
+		p, ok := e.extern.find(e, h, key)
 		if ok {
 			return p.getValue(), true
 		} else {
@@ -405,7 +442,7 @@ func (m *ConcurrentHashMap) Load(key interface{}) (interface{}, bool) {
 		if e == nil {
 			break
 		}
-		if h == e.hash && key == e.getKey() {
+		if h == e.hash && keyEquals(key, e.getKey()) {
 			return e.getValue(), true
 		}
 	}
@@ -417,16 +454,115 @@ func (m *ConcurrentHashMap) Contains(key interface{}) bool {
 	return ok
 }
 
+// Store panics if key or value is nil or key is not hashable, see TryStore
 func (m *ConcurrentHashMap) Store(key, value interface{}) interface{} {
+	old, err := m.storeVal(key, value, false)
+	if err != nil {
+		panic(err)
+	}
+	return old
+}
+
+// TryStore returns the previous value of key, or ErrNilElement if key or
+// value is nil, ErrIncomparable if key is not hashable
+func (m *ConcurrentHashMap) TryStore(key, value interface{}) (interface{}, error) {
 	return m.storeVal(key, value, false)
 }
 
-func (m *ConcurrentHashMap) storeVal(key, value interface{}, onlyIfAbsent bool) interface{} {
+// LoadOrStore returns the existing value of key if present, otherwise it
+// stores value and returns it. loaded is true if the value was loaded.
+// panics if key or value is nil or key is not hashable
+func (m *ConcurrentHashMap) LoadOrStore(key, value interface{}) (actual interface{}, loaded bool) {
+	old, err := m.storeVal(key, value, true)
+	if err != nil {
+		panic(err)
+	}
+	if old != nil {
+		return old, true
+	}
+	return value, false
+}
+
+// Delete removes key, return true if it was present
+func (m *ConcurrentHashMap) Delete(key interface{}) bool {
+	_, ok := m.LoadAndDelete(key)
+	return ok
+}
+
+// LoadAndDelete removes key, return its previous value if it was present
+func (m *ConcurrentHashMap) LoadAndDelete(key interface{}) (interface{}, bool) {
+	if key == nil {
+		panic("key is nil!")
+	}
+	hc, ok := hash(key)
+	if !ok {
+		return nil, false
+	}
+	return m.removeNode(key, spread(hc))
+}
+
+func (m *ConcurrentHashMap) removeNode(key interface{}, h int32) (interface{}, bool) {
+	for {
+		tab := m.getTable()
+		if tab == nil || len(*tab) == 0 {
+			return nil, false
+		}
+		n := int32(len(*tab))
+		i := (n - 1) & h
+		f := tabAt(tab, i)
+		if f == nil {
+			return nil, false
+		}
+		fh := f.hash
+		if fh == moved {
+			m.helpTransfer(tab, f)
+			continue
+		}
+		var oldVal interface{} = nil
+		validated := false
+		f.m.Lock()
+		// re-check
+		if tabAt(tab, i) == f {
+			if fh >= 0 {
+				validated = true
+				var pred *node
+				for e := f; e != nil; e = e.getNext() {
+					if e.hash == h && keyEquals(key, e.getKey()) {
+						oldVal = e.getValue()
+						if pred != nil {
+							atomic.StorePointer(&pred.next, atomic.LoadPointer(&e.next))
+						} else {
+							setTabAt(tab, i, e.getNext())
+						}
+						break
+					}
+					pred = e
+				}
+			} else if f.extern.isTreeNode() {
+				panic("NYI")
+			}
+		}
+		f.m.Unlock()
+		if validated {
+			if oldVal == nil {
+				return nil, false
+			}
+			m.addCount(-1, -1)
+			return oldVal, true
+		}
+	}
+}
+
+func (m *ConcurrentHashMap) storeVal(key, value interface{}, onlyIfAbsent bool) (interface{}, error) {
 	if key == nil || value == nil {
-		panic("key or value is null")
+		return nil, ErrNilElement
 	}
 	var binCount int32 = 0
-	h := spread(hash(key))
+	hc, ok := hash(key)
+	if !ok {
+		return nil, ErrIncomparable
+	}
+	h := spread(hc)
 	for {
 		tab := m.getTable()
 		var n int32
@@ -463,12 +599,12 @@ func (m *ConcurrentHashMap) storeVal(key, value interface{}, onlyIfAbsent bool)
 						for e := f; ; binCount++ {
 							if e.hash == h {
 								ek := e.getKey()
-								if key == ek {
+								if keyEquals(key, ek) {
 									oldVal = e.getValue()
 									if !onlyIfAbsent {
 										e.val = unsafe.Pointer(&value)
-										break
 									}
+									break
 								}
 							}
 							pred := e
@@ -489,7 +625,7 @@ func (m *ConcurrentHashMap) storeVal(key, value interface{}, onlyIfAbsent bool)
 							m.treeifyBin(tab, i)
 						}
 						if oldVal != nil {
-							return oldVal
+							return oldVal, nil
 						}
 						break
 					}
@@ -498,7 +634,80 @@ func (m *ConcurrentHashMap) storeVal(key, value interface{}, onlyIfAbsent bool)
 		}
 	}
 	m.addCount(1, binCount)
-	return nil
+	return nil, nil
+}
+
+// All2 yields the key and value pairs of the map. the traversal is weakly
+// consistent: it never yields a key twice, it reflects some of the
+// updates made after it started and it is safe with concurrent updates
+func (m *ConcurrentHashMap) All2() iter.Seq2[interface{}, interface{}] {
+	return func(yield func(interface{}, interface{}) bool) {
+		tab := m.getTable()
+		if tab == nil {
+			return
+		}
+		for i := 0; i < len(*tab); i++ {
+			if !traverseBin(tab, int32(i), yield) {
+				return
+			}
+		}
+	}
+}
+
+// Equals reports whether i is a ConcurrentHashMap with the same mappings,
+// values are compared with DefaultEquality. it is only exact when
+// both maps are quiescent
+func (m *ConcurrentHashMap) Equals(i interface{}) bool {
+	o, ok := i.(*ConcurrentHashMap)
+	if !ok {
+		return false
+	}
+	if o == m {
+		return true
+	}
+	n := 0
+	for k, v := range m.All2() {
+		ov, ok := o.Load(k)
+		if !ok || !equal(nil, v, ov) {
+			return false
+		}
+		n++
+	}
+	return n == o.Size()
+}
+
+// HashCode is the sum of Hash(key) ^ Hash(value) of all mappings
+func (m *ConcurrentHashMap) HashCode() int {
+	h := 0
+	for k, v := range m.All2() {
+		h += Hash(k) ^ Hash(v)
+	}
+	return h
+}
+
+// traverseBin visits bin i of tab, following the forwarding node to
+// the two bins it was split into. return false if yield stopped the traversal
+func traverseBin(tab *[]unsafe.Pointer, i int32, yield func(interface{}, interface{}) bool) bool {
+	e := tabAt(tab, i)
+	if e == nil {
+		return true
+	}
+	if e.hash < 0 {
+		if e.extern.isForwardNode() {
+			nextTab := e.extern.(*forwardingNode).nextTable
+			n := int32(len(*tab))
+			return traverseBin(nextTab, i, yield) && traverseBin(nextTab, i+n, yield)
+		}
+		// don't drop the entries of a tree bin silently
+		panic("treeify not implement yet")
+	}
+	for ; e != nil; e = e.getNext() {
+		v := e.getValue()
+		if v != nil && !yield(e.getKey(), v) {
+			return false
+		}
+	}
+	return true
 }
 
 // Helps transfer if a resize is in progress.
@@ -149,3 +149,107 @@ func TestMultiGoroutine(t *testing.T) {
 	cmap.printCountDetail()
 	fmt.Println("end")
 }
+
+func TestConcurrentHashMap_All2(t *testing.T) {
+	cmap := NewConcurrentHashMap(4, 4)
+	for range cmap.All2() {
+		t.Fatal("empty map should yield nothing")
+	}
+	total := 1000
+	for i := 0; i < total; i++ {
+		cmap.Store(keyObject2{i: i}, i)
+	}
+	seen := make(map[int]bool)
+	for k, v := range cmap.All2() {
+		i := k.(keyObject2).i
+		if v != i {
+			t.Fatal("value should match key")
+		}
+		if seen[i] {
+			t.Fatal("key yielded twice")
+		}
+		seen[i] = true
+	}
+	if len(seen) != total {
+		t.Fatalf("all2 count is %d\n", len(seen))
+	}
+	cnt := 0
+	for range cmap.All2() {
+		cnt++
+		if cnt == 10 {
+			break
+		}
+	}
+
+	tab := []unsafe.Pointer{unsafe.Pointer(&node{hash: treebin, extern: &baseNode{}})}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		traverseBin(&tab, 0, func(interface{}, interface{}) bool { return true })
+		return
+	}()
+	if !r {
+		t.Fatal("traversal of a tree bin should panic rather than skip it")
+	}
+}
+
+func TestConcurrentHashMap_Equals(t *testing.T) {
+	m1 := NewConcurrentHashMap(4, 4)
+	m2 := NewConcurrentHashMap(16, 4)
+	if !m1.Equals(m2) || m1.HashCode() != m2.HashCode() {
+		t.Fatal("empty maps should be equal")
+	}
+	for i := 0; i < 100; i++ {
+		m1.Store(keyObject2{i: i}, newSampleItem(i))
+		m2.Store(keyObject2{i: 99 - i}, newSampleItem(99-i))
+	}
+	if !m1.Equals(m2) || !m2.Equals(m1) || m1.HashCode() != m2.HashCode() {
+		t.Fatal("maps with the same mappings should be equal")
+	}
+	m2.Store(keyObject2{i: 0}, newSampleItem(100))
+	if m1.Equals(m2) || m2.Equals(m1) {
+		t.Fatal("maps with other values should not be equal")
+	}
+	m2.Store(keyObject2{i: 0}, newSampleItem(0))
+	m2.Store(keyObject2{i: 100}, newSampleItem(100))
+	if m1.Equals(m2) || m2.Equals(m1) {
+		t.Fatal("maps with other keys should not be equal")
+	}
+	if !m1.Equals(m1) || m1.Equals(struct{}{}) {
+		t.Fatal("equals error")
+	}
+}
+
+func TestConcurrentHashMap_Delete(t *testing.T) {
+	cmap := NewConcurrentHashMap(4, 4)
+	if cmap.Delete(keyObject2{i: 0}) {
+		t.Fatal("delete of a missing key should return false")
+	}
+	total := 1000
+	for i := 0; i < total; i++ {
+		cmap.Store(keyObject2{i: i}, i)
+	}
+	for i := 0; i < total; i += 2 {
+		if v, ok := cmap.LoadAndDelete(keyObject2{i: i}); !ok || v != i {
+			t.Fatal("load and delete should return the value")
+		}
+	}
+	if cmap.Size() != total/2 || cmap.Delete(keyObject2{i: 0}) {
+		t.Fatalf("cmap size is %d\n", cmap.Size())
+	}
+	for i := 0; i < total; i++ {
+		if _, ok := cmap.Load(keyObject2{i: i}); ok != (i%2 == 1) {
+			t.Fatal("only odd keys should be left")
+		}
+	}
+	if v, loaded := cmap.LoadOrStore(keyObject2{i: 1}, -1); !loaded || v != 1 {
+		t.Fatal("load or store should load the existing value")
+	}
+	if v, loaded := cmap.LoadOrStore(keyObject2{i: 0}, -1); loaded || v != -1 {
+		t.Fatal("load or store should store the value")
+	}
+	if cmap.Size() != total/2+1 {
+		t.Fatalf("cmap size is %d\n", cmap.Size())
+	}
+}
@@ -0,0 +1,194 @@
+package guc
+
+import (
+	"iter"
+)
+
+var _ Set = new(ConcurrentHashSet)
+
+// value of all the keys of the backing map
+var present interface{} = true
+
+// ConcurrentHashSet is a thread safe Set backed by the bins of a
+// ConcurrentHashMap, for large sets and frequent membership tests.
+// Elements follow the key rules of ConcurrentHashMap: Hashable elements
+// are compared with Equals, others with ==, nil and elements which
+// aren't hashable can't be added. Neither can elements implementing Object
+// but not Hashable, their Equals doesn't agree with the map hash.
+//
+// Iteration is weakly consistent, see ConcurrentHashMap.All2, and
+// Size is exact only when the set is quiescent
+type ConcurrentHashSet struct {
+	AbstractCollection
+	m *ConcurrentHashMap
+}
+
+func NewConcurrentHashSet() *ConcurrentHashSet {
+	return NewConcurrentHashSetWithCapacity(defaultCapacity)
+}
+
+func NewConcurrentHashSetWithCapacity(initialCapacity int32) *ConcurrentHashSet {
+	set := &ConcurrentHashSet{m: NewConcurrentHashMap(initialCapacity, 1)}
+	set.InitAbstractCollection(set)
+	return set
+}
+
+// NewConcurrentHashSetFromCollection creates a set holding the elements
+// of coll, without duplicates
+func NewConcurrentHashSetFromCollection(coll Collection) *ConcurrentHashSet {
+	set := NewConcurrentHashSetWithCapacity(int32(coll.Size()))
+	set.AddAll(coll)
+	return set
+}
+
+// Iterator returns a snapshot iterator, its Remove removes the element
+// from the set
+func (this *ConcurrentHashSet) Iterator() Iterator {
+	return &snapshotIter{data: this.ToArray(), last: -1, remove: this.Remove}
+}
+
+// All yields the elements in no particular order
+func (this *ConcurrentHashSet) All() iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for k := range this.m.All2() {
+			if !yield(k) {
+				return
+			}
+		}
+	}
+}
+
+func (this *ConcurrentHashSet) ForEach(consumer func(i interface{})) {
+	for k := range this.m.All2() {
+		consumer(k)
+	}
+}
+
+func (this *ConcurrentHashSet) Size() int {
+	return this.m.Size()
+}
+
+func (this *ConcurrentHashSet) IsEmpty() bool {
+	return this.m.IsEmpty()
+}
+
+// checkElement returns ErrIncomparable if i can't be an element even
+// though the map accepts it as key
+func checkElement(i interface{}) error {
+	if _, ok := i.(Object); ok {
+		if _, ok := i.(Hashable); !ok {
+			return ErrIncomparable
+		}
+	}
+	return nil
+}
+
+func (this *ConcurrentHashSet) Contains(i interface{}) bool {
+	if i == nil || checkElement(i) != nil {
+		return false
+	}
+	return this.m.Contains(i)
+}
+
+// TryContains is like Contains, it returns ErrIncomparable if i isn't
+// hashable
+func (this *ConcurrentHashSet) TryContains(i interface{}) (bool, error) {
+	if _, ok := hash(i); i != nil && (!ok || checkElement(i) != nil) {
+		return false, ErrIncomparable
+	}
+	return this.Contains(i), nil
+}
+
+// SetEquality panics, the elements are keys of the backing map which
+// always follow DefaultEquality
+func (this *ConcurrentHashSet) SetEquality(equality Equality) {
+	panic("set equality is not supported")
+}
+
+func (this *ConcurrentHashSet) ToArray() []interface{} {
+	result := make([]interface{}, 0, this.Size())
+	for k := range this.m.All2() {
+		result = append(result, k)
+	}
+	return result
+}
+
+// Add panics if i is nil or not hashable, see TryAdd
+func (this *ConcurrentHashSet) Add(i interface{}) bool {
+	if err := checkElement(i); err != nil {
+		panic(err)
+	}
+	_, loaded := this.m.LoadOrStore(i, present)
+	return !loaded
+}
+
+// TryAdd inserts i, return ErrNilElement if i is nil,
+// ErrIncomparable if it isn't hashable
+func (this *ConcurrentHashSet) TryAdd(i interface{}) error {
+	if err := checkElement(i); err != nil {
+		return err
+	}
+	_, err := this.m.storeVal(i, present, true)
+	return err
+}
+
+func (this *ConcurrentHashSet) Remove(i interface{}) bool {
+	if i == nil || checkElement(i) != nil {
+		return false
+	}
+	return this.m.Delete(i)
+}
+
+// TryRemove is like Remove, it returns ErrIncomparable if i isn't
+// hashable
+func (this *ConcurrentHashSet) TryRemove(i interface{}) (bool, error) {
+	if _, ok := hash(i); i != nil && (!ok || checkElement(i) != nil) {
+		return false, ErrIncomparable
+	}
+	return this.Remove(i), nil
+}
+
+func (this *ConcurrentHashSet) Clear() {
+	for k := range this.m.All2() {
+		this.m.Delete(k)
+	}
+}
+
+func (this *ConcurrentHashSet) Union(s Set) Set {
+	r := NewConcurrentHashSetFromCollection(this)
+	r.AddAll(s)
+	return r
+}
+
+func (this *ConcurrentHashSet) Intersect(s Set) Set {
+	r := NewConcurrentHashSet()
+	for k := range this.m.All2() {
+		if s.Contains(k) {
+			r.Add(k)
+		}
+	}
+	return r
+}
+
+func (this *ConcurrentHashSet) Difference(s Set) Set {
+	r := NewConcurrentHashSet()
+	for k := range this.m.All2() {
+		if !s.Contains(k) {
+			r.Add(k)
+		}
+	}
+	return r
+}
+
+// Equals follows the rules for sets, see Collection
+func (this *ConcurrentHashSet) Equals(i interface{}) bool {
+	if s, ok := i.(*ConcurrentHashSet); ok && s == this {
+		return true
+	}
+	s, ok := i.(Set)
+	return ok && SetEquals(this, s)
+}
+
+func (this *ConcurrentHashSet) HashCode() int {
+	return SetHashCode(this)
+}
@@ -0,0 +1,89 @@
+package guc
+
+import (
+	"testing"
+)
+
+func TestConcurrentHashSet_Elements(t *testing.T) {
+	s := NewConcurrentHashSet()
+	if s.TryAdd(nil) != ErrNilElement || s.TryAdd([]int{1}) != ErrIncomparable {
+		t.Fatal("try add of nil and unhashable elements should fail")
+	}
+	if s.Contains(nil) || s.Contains([]int{1}) || s.Remove(nil) {
+		t.Fatal("set should not contain nil or unhashable elements")
+	}
+	if _, err := s.TryContains([]int{1}); err != ErrIncomparable {
+		t.Fatal("try contains of an unhashable element should return ErrIncomparable")
+	}
+	if _, err := s.TryRemove([]int{1}); err != ErrIncomparable {
+		t.Fatal("try remove of an unhashable element should return ErrIncomparable")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		s.SetEquality(DefaultEquality)
+		return
+	}()
+	if !r {
+		t.Fatal("set equality should panic rather than be ignored")
+	}
+	if !s.Add(&hashableKey{id: 1, name: "a"}) || s.Add(&hashableKey{id: 1, name: "b"}) {
+		t.Fatal("hashable elements should be compared with Equals")
+	}
+	if !s.Contains(&hashableKey{id: 1}) || s.Size() != 1 {
+		t.Fatal("equal hashable elements should be unique")
+	}
+	if s.TryAdd(newSampleItem(1)) != ErrIncomparable || s.Contains(newSampleItem(1)) {
+		t.Fatal("objects which aren't hashable should be rejected")
+	}
+	if _, err := s.TryContains(newSampleItem(1)); err != ErrIncomparable {
+		t.Fatal("try contains of an object which isn't hashable should return ErrIncomparable")
+	}
+	e := func() (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		s.Add(newSampleItem(1))
+		return
+	}()
+	if e != ErrIncomparable || s.Size() != 1 {
+		t.Fatal("add of an object which isn't hashable should panic with ErrIncomparable")
+	}
+	if !s.Remove(&hashableKey{id: 1}) || !s.IsEmpty() {
+		t.Fatal("remove of a hashable element error")
+	}
+	for i := 0; i < 1000; i++ {
+		s.Add(keyObject2{i: i})
+	}
+	c := NewCopyOnWriteArraySet()
+	for i := 999; i >= 0; i-- {
+		c.Add(keyObject2{i: i})
+	}
+	if !s.Equals(c) || !c.Equals(s) || s.HashCode() != c.HashCode() {
+		t.Fatal("sets with the same elements should be equal")
+	}
+	iter := s.Iterator()
+	for iter.HasNext() {
+		if iter.Next().(keyObject2).i%2 == 0 {
+			iter.Remove()
+		}
+	}
+	if s.Size() != 500 || s.Contains(keyObject2{i: 0}) || !s.Contains(keyObject2{i: 1}) {
+		t.Fatal("iterator remove error")
+	}
+	s.Clear()
+	if !s.IsEmpty() || s.Size() != 0 {
+		t.Fatal("set should be empty")
+	}
+}
+
+func TestCopyOnWriteArraySet_Order(t *testing.T) {
+	s := NewCopyOnWriteArraySetFromCollection(NewCopyOnWriteArrayListFromSlice([]interface{}{3, 1, 3, 2, 1}))
+	if !ListEquals(s, NewCopyOnWriteArrayListFromSlice([]interface{}{3, 1, 2})) {
+		t.Fatal("set should keep the insertion order without duplicates")
+	}
+	if s.Equals(NewCopyOnWriteArrayListFromSlice([]interface{}{3, 1, 2})) {
+		t.Fatal("set should not be equal to a list")
+	}
+}
@@ -0,0 +1,335 @@
+package guc
+
+import (
+	"iter"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+var _ Queue = new(ConcurrentPriorityQueue)
+
+// each shard of the queue keeps its own heap and lock
+type cpqShard struct {
+	lock  sync.Mutex
+	queue PriorityQueue
+	// head of the heap, readable without lock
+	// Volatile, type is *interface{}
+	top     unsafe.Pointer
+	padding [CacheLineSize]byte
+}
+
+// caller must hold the lock
+func (s *cpqShard) updateTop() {
+	if s.queue.IsEmpty() {
+		atomic.StorePointer(&s.top, nil)
+	} else {
+		v := s.queue.data.data[0]
+		atomic.StorePointer(&s.top, unsafe.Pointer(&v))
+	}
+}
+
+func (s *cpqShard) getTop() interface{} {
+	p := atomic.LoadPointer(&s.top)
+	if p == nil {
+		return nil
+	}
+	return *(*interface{})(p)
+}
+
+// ConcurrentPriorityQueue is a relaxed priority queue for high contention,
+// following the MultiQueue design: elements are spread over several heaps,
+// each one guarded by its own lock. Offer inserts into a random shard, Poll
+// removes the head of the better one of two random shards.
+//
+// The ordering is relaxed: Poll returns an element close to the head,
+// but not necessarily the one with the highest priority. Peek and Element
+// only look at the heads of the shards without locking. Size is exact only
+// when the queue is quiescent.
+type ConcurrentPriorityQueue struct {
+	AbstractQueue
+	shards     []cpqShard
+	comparator Comparator
+	// Volatile
+	count int64
+}
+
+// NewConcurrentPriorityQueue creates a queue with 2 shards per processor,
+// comparator may be nil if elements implement Comparable
+func NewConcurrentPriorityQueue(comparator Comparator) *ConcurrentPriorityQueue {
+	return NewConcurrentPriorityQueueWithShards(2*runtime.GOMAXPROCS(0), comparator)
+}
+
+func NewConcurrentPriorityQueueWithShards(shards int, comparator Comparator) *ConcurrentPriorityQueue {
+	queue, err := NewConcurrentPriorityQueueChecked(shards, comparator)
+	if err != nil {
+		panic(err)
+	}
+	return queue
+}
+
+// NewConcurrentPriorityQueueChecked returns ErrIllegalArgument
+// instead of panicking if shards is not positive
+func NewConcurrentPriorityQueueChecked(shards int, comparator Comparator) (*ConcurrentPriorityQueue, error) {
+	if shards <= 0 {
+		return nil, ErrIllegalArgument
+	}
+	queue := &ConcurrentPriorityQueue{
+		shards:     make([]cpqShard, shards),
+		comparator: comparator,
+	}
+	queue.InitAbstractQueue(queue)
+	for i := range queue.shards {
+		s := &queue.shards[i]
+		s.queue.data.queue = &s.queue
+		s.queue.data.comparator = comparator
+	}
+	return queue, nil
+}
+
+func (this *ConcurrentPriorityQueue) randomShard() *cpqShard {
+	return &this.shards[Fastrand()%uint32(len(this.shards))]
+}
+
+// better returns the shard with the higher priority head, nil if both are empty
+func (this *ConcurrentPriorityQueue) better(s1, s2 *cpqShard) *cpqShard {
+	t1, t2 := s1.getTop(), s2.getTop()
+	if t1 == nil {
+		if t2 == nil {
+			return nil
+		}
+		return s2
+	}
+	if t2 == nil {
+		return s1
+	}
+	if compare(this.comparator, t1, t2) <= 0 {
+		return s1
+	}
+	return s2
+}
+
+// best scans the heads of all shards, return the shard with the best head
+// and the head it compared, nil if all are empty
+func (this *ConcurrentPriorityQueue) best() (*cpqShard, interface{}) {
+	var r *cpqShard
+	var top interface{}
+	for i := range this.shards {
+		s := &this.shards[i]
+		t := s.getTop()
+		if t != nil && (top == nil || compare(this.comparator, t, top) < 0) {
+			r, top = s, t
+		}
+	}
+	return r, top
+}
+
+// SetEquality sets the strategy used by Contains and Remove,
+// nil means DefaultEquality
+func (this *ConcurrentPriorityQueue) SetEquality(equality Equality) {
+	for idx := range this.shards {
+		s := &this.shards[idx]
+		s.lock.Lock()
+		s.queue.SetEquality(equality)
+		s.lock.Unlock()
+	}
+}
+
+func (this *ConcurrentPriorityQueue) Iterator() Iterator {
+	return &snapshotIter{data: this.ToArray(), last: -1, remove: this.removeEq}
+}
+
+// All yields a snapshot of the elements in no particular order
+func (this *ConcurrentPriorityQueue) All() iter.Seq[any] {
+	return sliceSeq(this.ToArray())
+}
+
+func (this *ConcurrentPriorityQueue) Size() int {
+	c := atomic.LoadInt64(&this.count)
+	if c < 0 {
+		return 0
+	}
+	if c > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(c)
+}
+
+func (this *ConcurrentPriorityQueue) IsEmpty() bool {
+	s, _ := this.best()
+	return s == nil
+}
+
+func (this *ConcurrentPriorityQueue) Contains(i interface{}) bool {
+	r, _ := this.TryContains(i)
+	return r
+}
+
+// TryContains is like Contains, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *ConcurrentPriorityQueue) TryContains(i interface{}) (bool, error) {
+	var firstErr error
+	for idx := range this.shards {
+		s := &this.shards[idx]
+		s.lock.Lock()
+		r, err := s.queue.TryContains(i)
+		s.lock.Unlock()
+		if r {
+			return true, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return false, firstErr
+}
+
+func (this *ConcurrentPriorityQueue) ToArray() []interface{} {
+	result := make([]interface{}, 0, this.Size())
+	for idx := range this.shards {
+		s := &this.shards[idx]
+		s.lock.Lock()
+		result = append(result, s.queue.data.data...)
+		s.lock.Unlock()
+	}
+	return result
+}
+
+func (this *ConcurrentPriorityQueue) Remove(i interface{}) bool {
+	r, _ := this.TryRemove(i)
+	return r
+}
+
+// TryRemove is like Remove, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *ConcurrentPriorityQueue) TryRemove(i interface{}) (bool, error) {
+	return this.remove((*PriorityQueue).TryRemove, i)
+}
+
+// removeEq is like Remove but matches the element by identity
+func (this *ConcurrentPriorityQueue) removeEq(i interface{}) bool {
+	r, _ := this.remove(func(q *PriorityQueue, i interface{}) (bool, error) {
+		return q.removeEq(i), nil
+	}, i)
+	return r
+}
+
+func (this *ConcurrentPriorityQueue) remove(remove func(*PriorityQueue, interface{}) (bool, error), i interface{}) (bool, error) {
+	var firstErr error
+	for idx := range this.shards {
+		s := &this.shards[idx]
+		s.lock.Lock()
+		r, err := remove(&s.queue, i)
+		if r {
+			s.updateTop()
+		}
+		s.lock.Unlock()
+		if r {
+			atomic.AddInt64(&this.count, -1)
+			return true, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return false, firstErr
+}
+
+func (this *ConcurrentPriorityQueue) RemoveIf(predicate func(i interface{}) bool) bool {
+	for idx := range this.shards {
+		s := &this.shards[idx]
+		s.lock.Lock()
+		r := s.queue.RemoveIf(predicate)
+		if r {
+			s.updateTop()
+		}
+		s.lock.Unlock()
+		if r {
+			atomic.AddInt64(&this.count, -1)
+			return true
+		}
+	}
+	return false
+}
+
+func (this *ConcurrentPriorityQueue) RetainAll(coll Collection) bool {
+	// coll may be this queue, so it is queried with no shard locked
+	var removing []interface{}
+	for _, v := range this.ToArray() {
+		if !coll.Contains(v) {
+			removing = append(removing, v)
+		}
+	}
+	changed := false
+	for _, v := range removing {
+		if this.removeEq(v) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+func (this *ConcurrentPriorityQueue) Clear() {
+	for idx := range this.shards {
+		s := &this.shards[idx]
+		s.lock.Lock()
+		removed := s.queue.Size()
+		s.queue.Clear()
+		s.updateTop()
+		s.lock.Unlock()
+		atomic.AddInt64(&this.count, -int64(removed))
+	}
+}
+
+func (this *ConcurrentPriorityQueue) Offer(i interface{}) bool {
+	s := this.randomShard()
+	// pick another shard when contended, block on the second try
+	if !s.lock.TryLock() {
+		s = this.randomShard()
+		s.lock.Lock()
+	}
+	s.queue.Offer(i)
+	s.updateTop()
+	s.lock.Unlock()
+	atomic.AddInt64(&this.count, 1)
+	return true
+}
+
+// TryAdd inserts i, return ErrNilElement if i is nil
+func (this *ConcurrentPriorityQueue) TryAdd(i interface{}) error {
+	if i == nil {
+		return ErrNilElement
+	}
+	this.Offer(i)
+	return nil
+}
+
+// Poll removes an element close to the head, see ConcurrentPriorityQueue
+func (this *ConcurrentPriorityQueue) Poll() interface{} {
+	for {
+		s := this.better(this.randomShard(), this.randomShard())
+		if s == nil {
+			// both shards are empty, fallback to a full scan
+			s, _ = this.best()
+			if s == nil {
+				return nil
+			}
+		}
+		s.lock.Lock()
+		i := s.queue.Poll()
+		s.updateTop()
+		s.lock.Unlock()
+		if i != nil {
+			atomic.AddInt64(&this.count, -1)
+			return i
+		}
+	}
+}
+
+// Peek returns the best head of all shards, without locking
+func (this *ConcurrentPriorityQueue) Peek() interface{} {
+	_, top := this.best()
+	return top
+}
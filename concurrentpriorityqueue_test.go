@@ -0,0 +1,183 @@
+package guc
+
+import (
+	"sync"
+	"testing"
+)
+
+func newPreparedConcurrentPriorityQueue(shards int) *ConcurrentPriorityQueue {
+	p := NewConcurrentPriorityQueueWithShards(shards, nil)
+	p.Add(newSampleItem(6))
+	p.Add(newSampleItem(8))
+	p.Add(newSampleItem(3))
+	p.Add(newSampleItem(6))
+	p.Add(newSampleItem(33))
+	p.Add(newSampleItem(7))
+	p.Add(newSampleItem(2))
+	return p
+}
+
+func TestNewConcurrentPriorityQueue(t *testing.T) {
+	p := NewConcurrentPriorityQueue(nil)
+	if !p.IsEmpty() || p.Size() != 0 {
+		t.Fatal("queue should be empty")
+	}
+	if p.Poll() != nil || p.Peek() != nil {
+		t.Fatal("poll and peek of empty queue should be nil")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		p.RemoveHead()
+		return
+	}()
+	if !r {
+		t.Fatal("should panic when RemoveHead of an empty queue")
+	}
+}
+
+func TestConcurrentPriorityQueue_SingleShardOrder(t *testing.T) {
+	p := newPreparedConcurrentPriorityQueue(1)
+	if p.Peek().(*sampleItem).Value != 2 {
+		t.Fatal("head should be value 2")
+	}
+	prev := -1
+	for !p.IsEmpty() {
+		v := p.Poll().(*sampleItem).Value
+		if v < prev {
+			t.Fatal("single shard queue should be strictly ordered")
+		}
+		prev = v
+	}
+	if p.Size() != 0 {
+		t.Fatal("queue size should be 0")
+	}
+}
+
+func TestConcurrentPriorityQueue_Collection(t *testing.T) {
+	p := newPreparedConcurrentPriorityQueue(4)
+	if p.Size() != 7 || len(p.ToArray()) != 7 {
+		t.Fatal("queue size should be 7")
+	}
+	if p.Peek().(*sampleItem).Value != 2 {
+		t.Fatal("peek should return the best head of all shards")
+	}
+	if !p.Contains(newSampleItem(33)) || p.Contains(newSampleItem(100)) {
+		t.Fatal("contains result error")
+	}
+	if !p.Remove(newSampleItem(33)) || p.Remove(newSampleItem(33)) {
+		t.Fatal("remove result error")
+	}
+	if !p.RemoveIf(func(i interface{}) bool { return i.(*sampleItem).Value == 8 }) {
+		t.Fatal("remove if result should be true")
+	}
+	if p.Size() != 5 {
+		t.Fatal("queue size should be 5")
+	}
+	c := NewPriority()
+	c.Add(newSampleItem(6))
+	c.Add(newSampleItem(2))
+	if !p.RetainAll(c) {
+		t.Fatal("retain all result should be true")
+	}
+	if p.Size() != 3 {
+		t.Fatal("queue size should be 3")
+	}
+	if p.RetainAll(p) || p.Size() != 3 {
+		t.Fatal("retain all of itself should change nothing")
+	}
+	cnt := 0
+	iter := p.Iterator()
+	for iter.HasNext() {
+		if iter.Next().(*sampleItem).Value == 2 {
+			iter.Remove()
+		}
+		cnt++
+	}
+	if cnt != 3 || p.Size() != 2 {
+		t.Fatal("iterator remove error")
+	}
+	p.Clear()
+	if !p.IsEmpty() || p.Size() != 0 {
+		t.Fatal("queue should be empty")
+	}
+}
+
+func TestConcurrentPriorityQueue_PeekDrainedShard(t *testing.T) {
+	p := NewConcurrentPriorityQueueWithShards(2, nil)
+	for idx, v := range []int{1, 5} {
+		s := &p.shards[idx]
+		s.queue.Offer(newSampleItem(v))
+		s.updateTop()
+	}
+	done := make(chan bool)
+	go func() {
+		// drain and refill the shard with the best head
+		s := &p.shards[0]
+		for i := 0; i < 200000; i++ {
+			s.lock.Lock()
+			i := s.queue.Poll()
+			s.updateTop()
+			s.queue.Offer(i)
+			s.updateTop()
+			s.lock.Unlock()
+		}
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		if p.Peek() == nil {
+			t.Fatal("peek should not be nil while a shard holds an element")
+		}
+	}
+}
+
+func TestConcurrentPriorityQueue_MultiGoroutine(t *testing.T) {
+	p := NewConcurrentPriorityQueueWithShards(8, nil)
+	gc := 4
+	countPerG := 10000
+	var wg sync.WaitGroup
+	for g := 0; g < gc; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for n := 0; n < countPerG; n++ {
+				p.Offer(newSampleItem(g*countPerG + n))
+			}
+		}(g)
+	}
+	wg.Wait()
+	if p.Size() != gc*countPerG {
+		t.Fatal("queue size should be", gc*countPerG)
+	}
+
+	seen := make([]bool, gc*countPerG)
+	results := make(chan []int, gc)
+	for g := 0; g < gc; g++ {
+		go func() {
+			polled := make([]int, 0)
+			for i := p.Poll(); i != nil; i = p.Poll() {
+				polled = append(polled, i.(*sampleItem).Value)
+			}
+			results <- polled
+		}()
+	}
+	total := 0
+	for g := 0; g < gc; g++ {
+		for _, v := range <-results {
+			if seen[v] {
+				t.Fatal("element polled twice:", v)
+			}
+			seen[v] = true
+			total++
+		}
+	}
+	if total != gc*countPerG || !p.IsEmpty() {
+		t.Fatal("all elements should be polled")
+	}
+}
@@ -0,0 +1,140 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// conditionLock is the lock a Condition releases while waiting
+type conditionLock interface {
+	// releaseAll releases the lock held by the caller, the returned func
+	// acquires it back in the same state
+	releaseAll() func()
+}
+
+type lockerCondition struct {
+	l sync.Locker
+}
+
+func (this lockerCondition) releaseAll() func() {
+	this.l.Unlock()
+	return this.l.Lock
+}
+
+// Condition is a condition variable like sync.Cond, whose waits can time
+// out or be cancelled. Each waiter sleeps on its own runtime semaphore and
+// Signal wakes the longest waiting one which hasn't given up, so a signal
+// is never lost on a cancelled waiter.
+//
+// The await methods must be called with the lock held, they release it
+// while waiting and hold it again on return. Like sync.Cond, callers should
+// check their predicate in a loop
+type Condition struct {
+	lock  conditionLock
+	mu    sync.Mutex
+	queue waitQueue
+}
+
+// NewCondition creates a condition bound to l, for a lock of this package
+// use Locker or the NewCondition method of the lock
+func NewCondition(l sync.Locker) *Condition {
+	return &Condition{lock: lockerCondition{l: l}}
+}
+
+// Await waits until signalled
+func (this *Condition) Await() {
+	this.await(nil, time.Time{})
+}
+
+// AwaitNanos waits at most timeout, return an estimate of the remaining
+// time, not positive if it timed out
+func (this *Condition) AwaitNanos(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return timeout
+	}
+	deadline := time.Now().Add(timeout)
+	this.await(nil, deadline)
+	return time.Until(deadline)
+}
+
+// AwaitUntil waits until signalled or the deadline, return false if the
+// deadline passed
+func (this *Condition) AwaitUntil(deadline time.Time) bool {
+	if !time.Now().Before(deadline) {
+		return false
+	}
+	return this.await(nil, deadline)
+}
+
+// AwaitContext waits until signalled, return ctx.Err() if ctx is done first
+func (this *Condition) AwaitContext(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !this.await(ctx, time.Time{}) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (this *Condition) await(ctx context.Context, deadline time.Time) bool {
+	w := &waiter{}
+	// queue before releasing the lock so no signal is missed
+	this.mu.Lock()
+	this.queue.pushBack(w)
+	this.mu.Unlock()
+	reacquire := this.releaseAll(w)
+	signalled := w.await(ctx, deadline)
+	if !signalled {
+		this.mu.Lock()
+		this.queue.remove(w)
+		this.mu.Unlock()
+	}
+	reacquire()
+	return signalled
+}
+
+// releaseAll releases the lock, w leaves the queue if the caller doesn't
+// hold it
+func (this *Condition) releaseAll(w *waiter) func() {
+	defer func() {
+		if r := recover(); r != nil {
+			this.mu.Lock()
+			this.queue.remove(w)
+			this.mu.Unlock()
+			panic(r)
+		}
+	}()
+	return this.lock.releaseAll()
+}
+
+// Signal wakes the longest waiting goroutine, if any
+func (this *Condition) Signal() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for w := this.queue.front(); w != nil; w = this.queue.front() {
+		this.queue.remove(w)
+		if w.signal() {
+			return
+		}
+	}
+}
+
+// SignalAll wakes all the waiting goroutines
+func (this *Condition) SignalAll() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	signalAll(&this.queue)
+}
+
+func (this *Condition) HasWaiters() bool {
+	return this.GetWaitQueueLength() > 0
+}
+
+// GetWaitQueueLength returns an estimate of the number of waiting goroutines
+func (this *Condition) GetWaitQueueLength() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.queue.size
+}
@@ -0,0 +1,125 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCondition(t *testing.T) {
+	var mu sync.Mutex
+	c := NewCondition(&mu)
+	mu.Lock()
+	if c.AwaitNanos(10*time.Millisecond) > 0 || c.AwaitUntil(time.Now().Add(time.Millisecond)) {
+		t.Fatal("await without signal should time out")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if c.AwaitContext(ctx) != context.DeadlineExceeded || c.HasWaiters() {
+		t.Fatal("await context should return context.DeadlineExceeded")
+	}
+	ready := false
+	go func() {
+		mu.Lock()
+		ready = true
+		c.Signal()
+		mu.Unlock()
+	}()
+	for !ready {
+		if c.AwaitNanos(time.Minute) <= 0 {
+			t.Fatal("signal should wake the waiter before the timeout")
+		}
+	}
+	mu.Unlock()
+}
+
+func TestCondition_SignalSkipsCancelled(t *testing.T) {
+	var mu sync.Mutex
+	c := NewCondition(&mu)
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for _, ctx := range []context.Context{ctx, context.Background()} {
+		go func(ctx context.Context) {
+			mu.Lock()
+			errs <- c.AwaitContext(ctx)
+			mu.Unlock()
+		}(ctx)
+		for c.GetWaitQueueLength() == 0 || (ctx == context.Background() && c.GetWaitQueueLength() != 2) {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	cancel()
+	if <-errs != context.Canceled {
+		t.Fatal("first waiter should be cancelled")
+	}
+	c.Signal()
+	if <-errs != nil {
+		t.Fatal("signal should wake the waiter which didn't give up")
+	}
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			mu.Lock()
+			errs <- c.AwaitContext(context.Background())
+			mu.Unlock()
+		}()
+	}
+	for c.GetWaitQueueLength() != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	c.SignalAll()
+	for i := 0; i < 3; i++ {
+		if <-errs != nil {
+			t.Fatal("signal all should wake all the waiters")
+		}
+	}
+}
+
+func TestCondition_ReentrantLock(t *testing.T) {
+	l := NewReentrantLock()
+	o1, o2 := NewOwner(), NewOwner()
+	c := l.NewCondition(o1)
+	done := make(chan bool)
+	go func() {
+		l.Lock(o1)
+		l.Lock(o1)
+		c.Await()
+		done <- l.GetHoldCount(o1) == 2
+		l.Unlock(o1)
+		l.Unlock(o1)
+	}()
+	for !c.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	if !l.TryLock(o2) {
+		t.Fatal("await should release all the holds")
+	}
+	c.Signal()
+	l.Unlock(o2)
+	if !<-done {
+		t.Fatal("await should restore the holds")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == ErrNotOwner
+		}()
+		c.Await()
+		return
+	}()
+	if !r {
+		t.Fatal("await without the lock should panic with ErrNotOwner")
+	}
+	l.Lock(o2)
+	r = func() (result bool) {
+		defer func() {
+			result = recover() == ErrNotOwner
+		}()
+		c.Await()
+		return
+	}()
+	if !r || l.GetHoldCount(o2) != 1 || c.HasWaiters() {
+		t.Fatal("await while another owner holds the lock should panic with ErrNotOwner")
+	}
+	l.Unlock(o2)
+}
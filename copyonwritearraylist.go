@@ -0,0 +1,398 @@
+package guc
+
+import (
+	"iter"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+var _ List = new(CopyOnWriteArrayList)
+
+// CopyOnWriteArrayList is a thread safe List for read-mostly data like
+// listeners: every mutation copies the backing slice under a lock and swaps
+// it atomically, so reads and iterations never block.
+//
+// Iterators work on the snapshot taken when they were created, they never
+// fail and don't reflect later mutations. They don't support Remove, Set
+// and Add. The zero value is an empty list
+type CopyOnWriteArrayList struct {
+	lock sync.Mutex
+	// Volatile, type is *[]interface{}, nil means empty
+	array unsafe.Pointer
+}
+
+func NewCopyOnWriteArrayList() *CopyOnWriteArrayList {
+	return &CopyOnWriteArrayList{}
+}
+
+// NewCopyOnWriteArrayListFromSlice creates a list holding a copy of arr
+func NewCopyOnWriteArrayListFromSlice(arr []interface{}) *CopyOnWriteArrayList {
+	list := &CopyOnWriteArrayList{}
+	list.setArray(append([]interface{}(nil), arr...))
+	return list
+}
+
+func NewCopyOnWriteArrayListFromCollection(coll Collection) *CopyOnWriteArrayList {
+	list := &CopyOnWriteArrayList{}
+	list.setArray(coll.ToArray())
+	return list
+}
+
+// getArray returns the current snapshot, which must not be modified
+func (this *CopyOnWriteArrayList) getArray() []interface{} {
+	p := atomic.LoadPointer(&this.array)
+	if p == nil {
+		return nil
+	}
+	return *(*[]interface{})(p)
+}
+
+// caller must hold the lock, arr must not be modified afterwards
+func (this *CopyOnWriteArrayList) setArray(arr []interface{}) {
+	atomic.StorePointer(&this.array, unsafe.Pointer(&arr))
+}
+
+func checkIndex(index, size int) {
+	if index < 0 || index >= size {
+		panic(ErrIndexOutOfRange)
+	}
+}
+
+func indexOf(arr []interface{}, i interface{}, from, to int) int {
+	for idx := from; idx < to; idx++ {
+		if equal(nil, arr[idx], i) {
+			return idx
+		}
+	}
+	return -1
+}
+
+type cowIterator struct {
+	snapshot []interface{}
+	// index of the element returned by a subsequent Next
+	cursor int
+}
+
+func (this *cowIterator) HasNext() bool {
+	return this.cursor < len(this.snapshot)
+}
+
+func (this *cowIterator) Next() interface{} {
+	if !this.HasNext() {
+		panic("no more elements")
+	}
+	r := this.snapshot[this.cursor]
+	this.cursor++
+	return r
+}
+
+func (this *cowIterator) HasPrevious() bool {
+	return this.cursor > 0
+}
+
+func (this *cowIterator) Previous() interface{} {
+	if !this.HasPrevious() {
+		panic("no more elements")
+	}
+	this.cursor--
+	return this.snapshot[this.cursor]
+}
+
+func (this *cowIterator) NextIndex() int {
+	return this.cursor
+}
+
+func (this *cowIterator) PreviousIndex() int {
+	return this.cursor - 1
+}
+
+func (this *cowIterator) Remove() {
+	panic("remove is not supported")
+}
+
+func (this *cowIterator) Set(i interface{}) {
+	panic("set is not supported")
+}
+
+func (this *cowIterator) Add(i interface{}) {
+	panic("add is not supported")
+}
+
+func (this *cowIterator) ForEachRemaining(consumer func(i interface{})) {
+	for this.HasNext() {
+		consumer(this.Next())
+	}
+}
+
+func (this *CopyOnWriteArrayList) Iterator() Iterator {
+	return &cowIterator{snapshot: this.getArray()}
+}
+
+func (this *CopyOnWriteArrayList) ListIterator() ListIterator {
+	return &cowIterator{snapshot: this.getArray()}
+}
+
+// All yields the elements of a snapshot in order
+func (this *CopyOnWriteArrayList) All() iter.Seq[any] {
+	return sliceSeq(this.getArray())
+}
+
+// Backward yields the elements of a snapshot in reverse order
+func (this *CopyOnWriteArrayList) Backward() iter.Seq[any] {
+	return sliceBackwardSeq(this.getArray())
+}
+
+func (this *CopyOnWriteArrayList) ForEach(consumer func(i interface{})) {
+	for _, v := range this.getArray() {
+		consumer(v)
+	}
+}
+
+func (this *CopyOnWriteArrayList) Size() int {
+	return len(this.getArray())
+}
+
+func (this *CopyOnWriteArrayList) IsEmpty() bool {
+	return this.Size() == 0
+}
+
+func (this *CopyOnWriteArrayList) Contains(i interface{}) bool {
+	return this.IndexOf(i) >= 0
+}
+
+func (this *CopyOnWriteArrayList) IndexOf(i interface{}) int {
+	arr := this.getArray()
+	return indexOf(arr, i, 0, len(arr))
+}
+
+func (this *CopyOnWriteArrayList) LastIndexOf(i interface{}) int {
+	arr := this.getArray()
+	for idx := len(arr) - 1; idx >= 0; idx-- {
+		if equal(nil, arr[idx], i) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func (this *CopyOnWriteArrayList) Get(index int) interface{} {
+	arr := this.getArray()
+	checkIndex(index, len(arr))
+	return arr[index]
+}
+
+func (this *CopyOnWriteArrayList) ToArray() []interface{} {
+	return append([]interface{}(nil), this.getArray()...)
+}
+
+func (this *CopyOnWriteArrayList) FillArray(arr []interface{}) []interface{} {
+	data := this.getArray()
+	if len(arr) >= len(data) {
+		copy(arr, data)
+		return arr[:len(data)]
+	} else {
+		return this.ToArray()
+	}
+}
+
+// SubList returns a new list holding a copy of the elements in [from, to)
+func (this *CopyOnWriteArrayList) SubList(from, to int) List {
+	arr := this.getArray()
+	if from < 0 || to > len(arr) || from > to {
+		panic(ErrIndexOutOfRange)
+	}
+	return NewCopyOnWriteArrayListFromSlice(arr[from:to])
+}
+
+func (this *CopyOnWriteArrayList) Set(index int, i interface{}) interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	checkIndex(index, len(arr))
+	old := arr[index]
+	newArr := append([]interface{}(nil), arr...)
+	newArr[index] = i
+	this.setArray(newArr)
+	return old
+}
+
+func (this *CopyOnWriteArrayList) Add(i interface{}) bool {
+	this.lock.Lock()
+	arr := this.getArray()
+	newArr := make([]interface{}, len(arr)+1)
+	copy(newArr, arr)
+	newArr[len(arr)] = i
+	this.setArray(newArr)
+	this.lock.Unlock()
+	return true
+}
+
+func (this *CopyOnWriteArrayList) AddAt(index int, i interface{}) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	if index < 0 || index > len(arr) {
+		panic(ErrIndexOutOfRange)
+	}
+	newArr := make([]interface{}, len(arr)+1)
+	copy(newArr, arr[:index])
+	newArr[index] = i
+	copy(newArr[index+1:], arr[index:])
+	this.setArray(newArr)
+}
+
+// AddIfAbsent appends i if the list doesn't contain it,
+// return true if it was added
+func (this *CopyOnWriteArrayList) AddIfAbsent(i interface{}) bool {
+	// cheap check without locking for the common case
+	if this.Contains(i) {
+		return false
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	if indexOf(arr, i, 0, len(arr)) >= 0 {
+		return false
+	}
+	newArr := make([]interface{}, len(arr)+1)
+	copy(newArr, arr)
+	newArr[len(arr)] = i
+	this.setArray(newArr)
+	return true
+}
+
+// AddAllAbsent appends the elements of coll which the list doesn't
+// contain yet, in the order of coll, return how many were added
+func (this *CopyOnWriteArrayList) AddAllAbsent(coll Collection) int {
+	elements := coll.ToArray()
+	if len(elements) == 0 {
+		return 0
+	}
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	newArr := make([]interface{}, len(arr), len(arr)+len(elements))
+	copy(newArr, arr)
+	for _, e := range elements {
+		if indexOf(newArr, e, 0, len(newArr)) < 0 {
+			newArr = append(newArr, e)
+		}
+	}
+	added := len(newArr) - len(arr)
+	if added > 0 {
+		this.setArray(newArr)
+	}
+	return added
+}
+
+func (this *CopyOnWriteArrayList) RemoveAt(index int) interface{} {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	checkIndex(index, len(arr))
+	old := arr[index]
+	this.setArray(removeIndex(arr, index))
+	return old
+}
+
+// removeIndex returns a copy of arr without the element at index
+func removeIndex(arr []interface{}, index int) []interface{} {
+	newArr := make([]interface{}, len(arr)-1)
+	copy(newArr, arr[:index])
+	copy(newArr[index:], arr[index+1:])
+	return newArr
+}
+
+func (this *CopyOnWriteArrayList) Remove(i interface{}) bool {
+	return this.RemoveIf(func(e interface{}) bool {
+		return equal(nil, e, i)
+	})
+}
+
+func (this *CopyOnWriteArrayList) RemoveIf(predicate func(i interface{}) bool) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	for idx, v := range arr {
+		if predicate(v) {
+			this.setArray(removeIndex(arr, idx))
+			return true
+		}
+	}
+	return false
+}
+
+func (this *CopyOnWriteArrayList) ContainsAll(coll Collection) bool {
+	arr := this.getArray()
+	for _, v := range coll.ToArray() {
+		if indexOf(arr, v, 0, len(arr)) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// AddAll appends all elements of coll at once
+func (this *CopyOnWriteArrayList) AddAll(coll Collection) bool {
+	elements := coll.ToArray()
+	if len(elements) == 0 {
+		return false
+	}
+	this.lock.Lock()
+	arr := this.getArray()
+	newArr := make([]interface{}, 0, len(arr)+len(elements))
+	newArr = append(append(newArr, arr...), elements...)
+	this.setArray(newArr)
+	this.lock.Unlock()
+	return true
+}
+
+// filter keeps the elements for which keep returns true,
+// return true if any element was removed
+func (this *CopyOnWriteArrayList) filter(keep func(i interface{}) bool) bool {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	arr := this.getArray()
+	newArr := make([]interface{}, 0, len(arr))
+	for _, v := range arr {
+		if keep(v) {
+			newArr = append(newArr, v)
+		}
+	}
+	if len(newArr) == len(arr) {
+		return false
+	}
+	this.setArray(newArr)
+	return true
+}
+
+// RemoveAll removes every occurrence of the elements of coll
+func (this *CopyOnWriteArrayList) RemoveAll(coll Collection) bool {
+	return this.filter(func(i interface{}) bool {
+		return !coll.Contains(i)
+	})
+}
+
+func (this *CopyOnWriteArrayList) RetainAll(coll Collection) bool {
+	return this.filter(coll.Contains)
+}
+
+func (this *CopyOnWriteArrayList) Clear() {
+	this.lock.Lock()
+	this.setArray(nil)
+	this.lock.Unlock()
+}
+
+// Equals follows the rules for lists, see Collection
+func (this *CopyOnWriteArrayList) Equals(i interface{}) bool {
+	if l, ok := i.(*CopyOnWriteArrayList); ok && l == this {
+		return true
+	}
+	l, ok := i.(List)
+	return ok && ListEquals(this, l)
+}
+
+func (this *CopyOnWriteArrayList) HashCode() int {
+	return ListHashCode(this)
+}
@@ -0,0 +1,89 @@
+package guc
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCopyOnWriteArrayList_Snapshot(t *testing.T) {
+	var l CopyOnWriteArrayList
+	l.Add(1)
+	l.Add(2)
+	iter := l.Iterator()
+	l.Add(3)
+	l.RemoveAt(0)
+	cnt := 0
+	for iter.HasNext() {
+		cnt += iter.Next().(int)
+	}
+	if cnt != 3 {
+		t.Fatal("iterator should not see later updates")
+	}
+	if l.Size() != 2 || l.Get(0) != 2 || l.Get(1) != 3 {
+		t.Fatal("list should hold 2 and 3")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		it := l.Iterator()
+		it.Next()
+		it.Remove()
+		return
+	}()
+	if !r {
+		t.Fatal("iterator remove should panic")
+	}
+	r = func() (result bool) {
+		defer func() {
+			result = recover() == ErrIndexOutOfRange
+		}()
+		l.Get(2)
+		return
+	}()
+	if !r {
+		t.Fatal("Get out of range should panic with ErrIndexOutOfRange")
+	}
+}
+
+func TestCopyOnWriteArrayList_AddIfAbsent(t *testing.T) {
+	l := NewCopyOnWriteArrayListFromSlice([]interface{}{1, 2})
+	if l.AddIfAbsent(1) || !l.AddIfAbsent(3) || l.Size() != 3 {
+		t.Fatal("add if absent error")
+	}
+	if l.AddAllAbsent(NewCopyOnWriteArrayListFromSlice([]interface{}{3, 4, 5, 4})) != 2 {
+		t.Fatal("add all absent should add 4 and 5 once")
+	}
+	if !ListEquals(l, NewCopyOnWriteArrayListFromSlice([]interface{}{1, 2, 3, 4, 5})) {
+		t.Fatal("list should hold 1 to 5")
+	}
+	if l.AddAllAbsent(l) != 0 {
+		t.Fatal("add all absent of itself should add nothing")
+	}
+
+	l.Clear()
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				l.AddIfAbsent(i)
+			}
+		}()
+	}
+	wg.Wait()
+	if l.Size() != 100 {
+		t.Fatal("concurrent add if absent should add every element once")
+	}
+}
+
+func TestCopyOnWriteArrayList_RemoveAll(t *testing.T) {
+	l := NewCopyOnWriteArrayListFromSlice([]interface{}{1, 2, 1, 3})
+	if !l.RemoveAll(NewCopyOnWriteArrayListFromSlice([]interface{}{1})) || l.Size() != 2 {
+		t.Fatal("remove all should remove every occurrence")
+	}
+	if !l.Equals(NewCopyOnWriteArrayListFromSlice([]interface{}{2, 3})) || l.Equals(NewPriority()) {
+		t.Fatal("equals error")
+	}
+}
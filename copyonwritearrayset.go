@@ -0,0 +1,123 @@
+package guc
+
+import (
+	"iter"
+)
+
+var _ Set = new(CopyOnWriteArraySet)
+
+// CopyOnWriteArraySet is a thread safe Set backed by a CopyOnWriteArrayList,
+// for tiny read-mostly sets: membership tests are linear, mutations copy
+// the whole set. Iterators work on a snapshot and don't support Remove.
+// The zero value is an empty set
+type CopyOnWriteArraySet struct {
+	list CopyOnWriteArrayList
+}
+
+func NewCopyOnWriteArraySet() *CopyOnWriteArraySet {
+	return &CopyOnWriteArraySet{}
+}
+
+// NewCopyOnWriteArraySetFromCollection creates a set holding the elements
+// of coll, without duplicates
+func NewCopyOnWriteArraySetFromCollection(coll Collection) *CopyOnWriteArraySet {
+	set := &CopyOnWriteArraySet{}
+	set.list.AddAllAbsent(coll)
+	return set
+}
+
+func (this *CopyOnWriteArraySet) Iterator() Iterator {
+	return this.list.Iterator()
+}
+
+// All yields the elements of a snapshot in insertion order
+func (this *CopyOnWriteArraySet) All() iter.Seq[any] {
+	return this.list.All()
+}
+
+func (this *CopyOnWriteArraySet) ForEach(consumer func(i interface{})) {
+	this.list.ForEach(consumer)
+}
+
+func (this *CopyOnWriteArraySet) Size() int {
+	return this.list.Size()
+}
+
+func (this *CopyOnWriteArraySet) IsEmpty() bool {
+	return this.list.IsEmpty()
+}
+
+func (this *CopyOnWriteArraySet) Contains(i interface{}) bool {
+	return this.list.Contains(i)
+}
+
+func (this *CopyOnWriteArraySet) ToArray() []interface{} {
+	return this.list.ToArray()
+}
+
+func (this *CopyOnWriteArraySet) FillArray(arr []interface{}) []interface{} {
+	return this.list.FillArray(arr)
+}
+
+func (this *CopyOnWriteArraySet) Add(i interface{}) bool {
+	return this.list.AddIfAbsent(i)
+}
+
+func (this *CopyOnWriteArraySet) Remove(i interface{}) bool {
+	return this.list.Remove(i)
+}
+
+func (this *CopyOnWriteArraySet) ContainsAll(coll Collection) bool {
+	return this.list.ContainsAll(coll)
+}
+
+func (this *CopyOnWriteArraySet) AddAll(coll Collection) bool {
+	return this.list.AddAllAbsent(coll) > 0
+}
+
+func (this *CopyOnWriteArraySet) RemoveAll(coll Collection) bool {
+	return this.list.RemoveAll(coll)
+}
+
+func (this *CopyOnWriteArraySet) RemoveIf(predicate func(i interface{}) bool) bool {
+	return this.list.RemoveIf(predicate)
+}
+
+func (this *CopyOnWriteArraySet) RetainAll(coll Collection) bool {
+	return this.list.RetainAll(coll)
+}
+
+func (this *CopyOnWriteArraySet) Clear() {
+	this.list.Clear()
+}
+
+func (this *CopyOnWriteArraySet) Union(s Set) Set {
+	r := NewCopyOnWriteArraySetFromCollection(this)
+	r.list.AddAllAbsent(s)
+	return r
+}
+
+func (this *CopyOnWriteArraySet) Intersect(s Set) Set {
+	r := NewCopyOnWriteArraySetFromCollection(this)
+	r.list.RetainAll(s)
+	return r
+}
+
+func (this *CopyOnWriteArraySet) Difference(s Set) Set {
+	r := NewCopyOnWriteArraySetFromCollection(this)
+	r.list.RemoveAll(s)
+	return r
+}
+
+// Equals follows the rules for sets, see Collection
+func (this *CopyOnWriteArraySet) Equals(i interface{}) bool {
+	if s, ok := i.(*CopyOnWriteArraySet); ok && s == this {
+		return true
+	}
+	s, ok := i.(Set)
+	return ok && SetEquals(this, s)
+}
+
+func (this *CopyOnWriteArraySet) HashCode() int {
+	return SetHashCode(this)
+}
@@ -0,0 +1,93 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CountDownLatch lets goroutines wait until a count of events reaches zero,
+// it can't be reset, see CyclicBarrier for that. Unlike sync.WaitGroup it
+// supports timed and cancellable waits, and counting down a latch at zero
+// does nothing
+type CountDownLatch struct {
+	mu sync.Mutex
+	// Volatile, written with mu held
+	count int64
+	// guarded by mu
+	queue waitQueue
+}
+
+// NewCountDownLatch panics with ErrIllegalArgument if count is negative
+func NewCountDownLatch(count int) *CountDownLatch {
+	if count < 0 {
+		panic(ErrIllegalArgument)
+	}
+	return &CountDownLatch{count: int64(count)}
+}
+
+// CountDown decrements the count, the waiters are released when it
+// reaches zero
+func (this *CountDownLatch) CountDown() {
+	if atomic.LoadInt64(&this.count) == 0 {
+		return
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.count == 0 {
+		return
+	}
+	atomic.StoreInt64(&this.count, this.count-1)
+	if this.count == 0 {
+		signalAll(&this.queue)
+	}
+}
+
+// GetCount returns the current count
+func (this *CountDownLatch) GetCount() int {
+	return int(atomic.LoadInt64(&this.count))
+}
+
+// Await blocks until the count reaches zero
+func (this *CountDownLatch) Await() {
+	this.await(nil, time.Time{})
+}
+
+// AwaitWithTimeout waits at most timeout for the count to reach zero,
+// return false if it didn't
+func (this *CountDownLatch) AwaitWithTimeout(timeout time.Duration) bool {
+	return this.await(nil, time.Now().Add(timeout))
+}
+
+// AwaitContext is like Await, it returns ctx.Err() if ctx is done first
+func (this *CountDownLatch) AwaitContext(ctx context.Context) error {
+	if this.await(ctx, time.Time{}) {
+		return nil
+	}
+	return ctx.Err()
+}
+
+func (this *CountDownLatch) await(ctx context.Context, deadline time.Time) bool {
+	if atomic.LoadInt64(&this.count) == 0 {
+		return true
+	}
+	if ctx != nil && ctx.Err() != nil {
+		return false
+	}
+	this.mu.Lock()
+	if this.count == 0 {
+		this.mu.Unlock()
+		return true
+	}
+	w := &waiter{}
+	this.queue.pushBack(w)
+	this.mu.Unlock()
+	if w.await(ctx, deadline) {
+		return true
+	}
+	this.mu.Lock()
+	this.queue.remove(w)
+	this.mu.Unlock()
+	return false
+}
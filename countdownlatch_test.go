@@ -0,0 +1,42 @@
+package guc
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCountDownLatch(t *testing.T) {
+	l := NewCountDownLatch(2)
+	if l.AwaitWithTimeout(10*time.Millisecond) || l.GetCount() != 2 {
+		t.Fatal("await should time out")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if l.AwaitContext(ctx) != context.Canceled {
+		t.Fatal("await context should return context.Canceled")
+	}
+	var released int32
+	done := make(chan bool)
+	for i := 0; i < 3; i++ {
+		go func() {
+			l.Await()
+			atomic.AddInt32(&released, 1)
+			done <- true
+		}()
+	}
+	l.CountDown()
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&released) != 0 || l.GetCount() != 1 {
+		t.Fatal("waiters should not be released before the count reaches zero")
+	}
+	l.CountDown()
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	l.CountDown()
+	if l.GetCount() != 0 || !l.AwaitWithTimeout(0) || l.AwaitContext(context.Background()) != nil {
+		t.Fatal("latch at zero should not block")
+	}
+}
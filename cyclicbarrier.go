@@ -0,0 +1,205 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// CyclicBarrier lets a fixed number of parties wait for each other, it
+// trips when the last one arrives and is reused by the next generation.
+//
+// A party which gives up, by timeout or context, breaks the barrier: the
+// waiting parties and the later ones get ErrBrokenBarrier until Reset.
+// The barrier action runs in the last arriving goroutine before the
+// others are released, without the barrier locked so it may use it:
+// parties arriving meanwhile wait for the next trip, whose action may
+// overlap, and a Reset breaks the generations whose action is running too.
+// If it panics the barrier is broken
+type CyclicBarrier struct {
+	mu            sync.Mutex
+	parties       int
+	barrierAction func()
+	// guarded by mu
+	gen *generation
+	// parties still expected in gen
+	count int
+	// the generations whose barrier action is running, they may overlap
+	tripping []*generation
+}
+
+// generation is a use of a CyclicBarrier, its waiters are released
+// together
+type generation struct {
+	broken bool
+	// the barrier action of this generation is running
+	tripping bool
+	queue    waitQueue
+}
+
+// NewCyclicBarrier panics with ErrIllegalArgument if parties isn't positive,
+// barrierAction may be nil
+func NewCyclicBarrier(parties int, barrierAction func()) *CyclicBarrier {
+	if parties <= 0 {
+		panic(ErrIllegalArgument)
+	}
+	return &CyclicBarrier{
+		parties:       parties,
+		barrierAction: barrierAction,
+		gen:           &generation{},
+		count:         parties,
+	}
+}
+
+// Await waits until all parties have arrived. return the arrival index,
+// parties-1 for the first and 0 for the last, or ErrBrokenBarrier
+func (this *CyclicBarrier) Await() (int, error) {
+	return this.await(nil, time.Time{})
+}
+
+// AwaitWithTimeout is like Await, it breaks the barrier and returns
+// ErrTimeout if the parties don't arrive within timeout
+func (this *CyclicBarrier) AwaitWithTimeout(timeout time.Duration) (int, error) {
+	return this.await(nil, time.Now().Add(timeout))
+}
+
+// AwaitContext is like Await, it breaks the barrier and returns ctx.Err()
+// if ctx is done first
+func (this *CyclicBarrier) AwaitContext(ctx context.Context) (int, error) {
+	return this.await(ctx, time.Time{})
+}
+
+func (this *CyclicBarrier) await(ctx context.Context, deadline time.Time) (int, error) {
+	this.mu.Lock()
+	g := this.gen
+	if g.broken {
+		this.mu.Unlock()
+		return 0, ErrBrokenBarrier
+	}
+	if ctx != nil && ctx.Err() != nil {
+		this.breakBarrier()
+		this.mu.Unlock()
+		return 0, ctx.Err()
+	}
+	this.count--
+	index := this.count
+	if index == 0 {
+		this.trip(g)
+		return 0, nil
+	}
+	w := &waiter{}
+	g.queue.pushBack(w)
+	this.mu.Unlock()
+
+	signalled := w.await(ctx, deadline)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for !signalled && g.tripping {
+		// too late to give up, wait for the action to release g
+		w = &waiter{}
+		g.queue.pushBack(w)
+		this.mu.Unlock()
+		signalled = w.await(nil, time.Time{})
+		this.mu.Lock()
+	}
+	if g.broken {
+		return 0, ErrBrokenBarrier
+	}
+	if signalled || g != this.gen {
+		return index, nil
+	}
+	g.queue.remove(w)
+	this.breakBarrier()
+	if ctx != nil && ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	return 0, ErrTimeout
+}
+
+// trip starts the next generation and releases g once the action has run,
+// must be called with mu held, it unlocks mu
+func (this *CyclicBarrier) trip(g *generation) {
+	if this.barrierAction == nil {
+		this.nextGeneration()
+		this.mu.Unlock()
+		return
+	}
+	this.gen = &generation{}
+	this.count = this.parties
+	g.tripping = true
+	this.tripping = append(this.tripping, g)
+	next := this.gen
+	this.mu.Unlock()
+	ran := false
+	defer func() {
+		this.mu.Lock()
+		defer this.mu.Unlock()
+		g.tripping = false
+		for idx, t := range this.tripping {
+			if t == g {
+				this.tripping = append(this.tripping[:idx], this.tripping[idx+1:]...)
+				break
+			}
+		}
+		if !ran {
+			g.broken = true
+			if this.gen == next {
+				this.breakBarrier()
+			}
+		}
+		signalAll(&g.queue)
+	}()
+	this.barrierAction()
+	ran = true
+}
+
+// must be called with mu held
+func (this *CyclicBarrier) nextGeneration() {
+	signalAll(&this.gen.queue)
+	this.gen = &generation{}
+	this.count = this.parties
+}
+
+// must be called with mu held
+func (this *CyclicBarrier) breakBarrier() {
+	this.gen.broken = true
+	this.count = this.parties
+	signalAll(&this.gen.queue)
+}
+
+func signalAll(q *waitQueue) {
+	for w := q.front(); w != nil; w = q.front() {
+		q.remove(w)
+		w.signal()
+	}
+}
+
+// Reset breaks the barrier for the waiting parties and starts a new
+// generation
+func (this *CyclicBarrier) Reset() {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	for _, g := range this.tripping {
+		g.broken = true
+		signalAll(&g.queue)
+	}
+	this.breakBarrier()
+	this.nextGeneration()
+}
+
+func (this *CyclicBarrier) IsBroken() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.gen.broken
+}
+
+func (this *CyclicBarrier) GetParties() int {
+	return this.parties
+}
+
+// GetNumberWaiting returns the number of parties waiting at the barrier
+func (this *CyclicBarrier) GetNumberWaiting() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.parties - this.count
+}
@@ -0,0 +1,189 @@
+package guc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCyclicBarrier(t *testing.T) {
+	trips := 0
+	b := NewCyclicBarrier(3, func() {
+		trips++
+	})
+	for round := 1; round <= 3; round++ {
+		var wg sync.WaitGroup
+		indexes := make(chan int, 3)
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				idx, err := b.Await()
+				if err != nil {
+					t.Error("await error", err)
+				}
+				indexes <- idx
+			}()
+		}
+		wg.Wait()
+		close(indexes)
+		sum := 0
+		for idx := range indexes {
+			sum += idx
+		}
+		if sum != 0+1+2 || trips != round || b.GetNumberWaiting() != 0 {
+			t.Fatalf("round %d: barrier error", round)
+		}
+	}
+}
+
+func TestCyclicBarrier_Broken(t *testing.T) {
+	b := NewCyclicBarrier(3, nil)
+	errs := make(chan error)
+	go func() {
+		_, err := b.Await()
+		errs <- err
+	}()
+	for b.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := b.AwaitWithTimeout(10 * time.Millisecond); err != ErrTimeout {
+		t.Fatal("timed await should return ErrTimeout")
+	}
+	if err := <-errs; err != ErrBrokenBarrier || !b.IsBroken() {
+		t.Fatal("waiting party should get ErrBrokenBarrier")
+	}
+	if _, err := b.Await(); err != ErrBrokenBarrier {
+		t.Fatal("broken barrier should fail immediately")
+	}
+	b.Reset()
+	if b.IsBroken() || b.GetNumberWaiting() != 0 {
+		t.Fatal("reset should repair the barrier")
+	}
+	go func() {
+		_, err := b.Await()
+		errs <- err
+	}()
+	for b.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	b.Reset()
+	if err := <-errs; err != ErrBrokenBarrier || b.IsBroken() {
+		t.Fatal("reset should break the current generation only")
+	}
+}
+
+func TestCyclicBarrier_ActionPanic(t *testing.T) {
+	b := NewCyclicBarrier(1, func() {
+		panic("action")
+	})
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == "action"
+		}()
+		b.Await()
+		return
+	}()
+	if !r || !b.IsBroken() {
+		t.Fatal("panicking action should break the barrier")
+	}
+}
+
+func TestCyclicBarrier_ActionUsesBarrier(t *testing.T) {
+	var b *CyclicBarrier
+	reset := false
+	running, proceed := make(chan bool), make(chan bool)
+	b = NewCyclicBarrier(2, func() {
+		if b.IsBroken() || b.GetNumberWaiting() != 0 {
+			t.Error("next generation should be empty while the action runs")
+		}
+		if reset {
+			b.Reset()
+			return
+		}
+		running <- true
+		<-proceed
+	})
+	errs := make(chan error, 3)
+	await := func() {
+		_, err := b.Await()
+		errs <- err
+	}
+	go await()
+	go await()
+	<-running
+	// a party arriving while the action runs waits for the next trip
+	go await()
+	for b.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	proceed <- true
+	if <-errs != nil || <-errs != nil {
+		t.Fatal("tripped parties should be released")
+	}
+	go await()
+	<-running
+	proceed <- true
+	if <-errs != nil || <-errs != nil {
+		t.Fatal("later party should trip the next generation")
+	}
+
+	reset = true
+	go await()
+	for b.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := b.Await(); err != nil {
+		t.Fatal("tripping party should not be broken by the reset of its action")
+	}
+	if <-errs != ErrBrokenBarrier || b.IsBroken() {
+		t.Fatal("reset by the action should break the tripped generation only")
+	}
+}
+
+func TestCyclicBarrier_OverlappingActions(t *testing.T) {
+	var calls int32
+	running, proceed := make(chan bool), make(chan bool)
+	b := NewCyclicBarrier(2, func() {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			running <- true
+			<-proceed
+		}
+	})
+	timed := make(chan error)
+	go func() {
+		_, err := b.AwaitWithTimeout(20 * time.Millisecond)
+		timed <- err
+	}()
+	for b.GetNumberWaiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	first := make(chan error)
+	go func() {
+		_, err := b.Await()
+		first <- err
+	}()
+	<-running
+	// the next generation trips and runs its action meanwhile
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := b.Await()
+			errs <- err
+		}()
+	}
+	if <-errs != nil || <-errs != nil {
+		t.Fatal("next generation should trip while the first action runs")
+	}
+	time.Sleep(40 * time.Millisecond)
+	select {
+	case <-timed:
+		t.Fatal("party of a tripped generation should wait for its action")
+	default:
+	}
+	proceed <- true
+	if <-first != nil || <-timed != nil || b.IsBroken() {
+		t.Fatal("tripped generation should be released once its action is done")
+	}
+}
@@ -0,0 +1,58 @@
+package guc
+
+import (
+	"reflect"
+)
+
+// DefaultEquality is used by all collections unless another one is set:
+// Object.Equals if the first element implements Object, otherwise Go ==,
+// ErrIncomparable if == would panic
+var DefaultEquality Equality = EqualityFunc(defaultEqual)
+
+// EqualityFunc is an ordinary function used as Equality
+type EqualityFunc func(o1, o2 interface{}) (bool, error)
+
+func (f EqualityFunc) Equal(o1, o2 interface{}) (bool, error) {
+	return f(o1, o2)
+}
+
+func defaultEqual(o1, o2 interface{}) (bool, error) {
+	if o, ok := o1.(Object); ok {
+		return o.Equals(o2), nil
+	}
+	if o1 == nil || o2 == nil {
+		return o1 == o2, nil
+	}
+	v1, v2 := reflect.ValueOf(o1), reflect.ValueOf(o2)
+	if v1.Type() != v2.Type() {
+		return false, nil
+	}
+	if !v1.Comparable() || !v2.Comparable() {
+		return false, ErrIncomparable
+	}
+	return o1 == o2, nil
+}
+
+// tryEqual applies e, or DefaultEquality if e is nil
+func tryEqual(e Equality, o1, o2 interface{}) (bool, error) {
+	if e == nil {
+		e = DefaultEquality
+	}
+	return e.Equal(o1, o2)
+}
+
+// equal is like tryEqual, incomparable elements are never equal
+func equal(e Equality, o1, o2 interface{}) bool {
+	r, err := tryEqual(e, o1, o2)
+	return err == nil && r
+}
+
+// identical reports whether o1 and o2 are the same element by ==, which
+// compares pointers by address. incomparable values have no identity,
+// e decides for them
+func identical(e Equality, o1, o2 interface{}) bool {
+	if o1 != nil && o2 != nil && !reflect.ValueOf(o1).Comparable() {
+		return equal(e, o1, o2)
+	}
+	return o1 == o2
+}
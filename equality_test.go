@@ -0,0 +1,133 @@
+package guc
+
+import (
+	"testing"
+)
+
+type hashableKey struct {
+	id   int
+	name string
+}
+
+func (this *hashableKey) Equals(i interface{}) bool {
+	dst, ok := i.(*hashableKey)
+	return ok && this.id == dst.id
+}
+
+func (this *hashableKey) HashCode() int {
+	return this.id
+}
+
+func TestDefaultEquality(t *testing.T) {
+	if r, err := DefaultEquality.Equal(1, 1); !r || err != nil {
+		t.Fatal("1 should be equal to 1")
+	}
+	if r, err := DefaultEquality.Equal(1, int64(1)); r || err != nil {
+		t.Fatal("values of different types should not be equal")
+	}
+	if r, _ := DefaultEquality.Equal(newSampleItem(1), newSampleItem(1)); !r {
+		t.Fatal("Object.Equals should be used")
+	}
+	if r, err := DefaultEquality.Equal(nil, nil); !r || err != nil {
+		t.Fatal("nil should be equal to nil")
+	}
+	if _, err := DefaultEquality.Equal([]int{1}, []int{1}); err != ErrIncomparable {
+		t.Fatal("slices should be incomparable")
+	}
+	if _, err := DefaultEquality.Equal(testS4{t: nil}, testS4{t: nil}); err != nil {
+		t.Fatal("struct should be comparable")
+	}
+}
+
+func TestPriorityQueue_ContainsPlainValues(t *testing.T) {
+	p := NewPriorityWithComparator(NaturalOrder())
+	p.Add(3)
+	p.Add(1)
+	if !p.Contains(3) || p.Contains(2) {
+		t.Fatal("contains result error")
+	}
+	if !p.Remove(1) || p.Remove(1) {
+		t.Fatal("remove result error")
+	}
+
+	s := NewPriorityWithComparator(ComparatorFunc(func(o1, o2 interface{}) int {
+		return len(o1.([]int)) - len(o2.([]int))
+	}))
+	s.Add([]int{1})
+	if s.Contains([]int{1}) || s.Remove([]int{1}) {
+		t.Fatal("incomparable elements should never be equal")
+	}
+	s.SetEquality(EqualityFunc(func(o1, o2 interface{}) (bool, error) {
+		return o1.([]int)[0] == o2.([]int)[0], nil
+	}))
+	if !s.Contains([]int{1}) {
+		t.Fatal("custom equality should be used")
+	}
+	s.SetEquality(nil)
+	if _, err := s.TryContains([]int{1}); err != ErrIncomparable {
+		t.Fatal("try contains should return ErrIncomparable")
+	}
+	if r, err := s.TryRemove([]int{1}); r || err != ErrIncomparable || s.Size() != 1 {
+		t.Fatal("try remove should return ErrIncomparable")
+	}
+	if r, err := p.TryRemove(3); !r || err != nil {
+		t.Fatal("try remove of a plain value should succeed")
+	}
+
+	b := NewPriorityBlockingQueueWithComparator(NaturalOrder())
+	b.Add("a")
+	if !b.Contains("a") || !b.Remove("a") {
+		t.Fatal("blocking queue should use default equality")
+	}
+	b.Add([]int{1})
+	if _, err := b.TryContains([]int{1}); err != ErrIncomparable {
+		t.Fatal("blocking queue try contains should return ErrIncomparable")
+	}
+	if _, err := b.TryRemove([]int{1}); err != ErrIncomparable {
+		t.Fatal("blocking queue try remove should return ErrIncomparable")
+	}
+
+	c := NewConcurrentPriorityQueueWithShards(1, NaturalOrder())
+	c.Add([]int{1})
+	if _, err := c.TryRemove([]int{1}); err != ErrIncomparable || c.Size() != 1 {
+		t.Fatal("concurrent queue try remove should return ErrIncomparable")
+	}
+}
+
+func TestConcurrentHashMap_HashableKey(t *testing.T) {
+	cmap := NewConcurrentHashMap(16, 4)
+	cmap.Store(&hashableKey{id: 1, name: "a"}, "v1")
+	v, ok := cmap.Load(&hashableKey{id: 1, name: "b"})
+	if !ok || v != "v1" {
+		t.Fatal("Hashable keys should be compared by Equals")
+	}
+	if cmap.Store(&hashableKey{id: 1}, "v2") != "v1" || cmap.Size() != 1 {
+		t.Fatal("store should replace the value of an equal key")
+	}
+	cmap.Store(keyObject2{i: 1}, "v3")
+	if v, ok := cmap.Load(keyObject2{i: 1}); !ok || v != "v3" {
+		t.Fatal("equal plain keys should be found by ==")
+	}
+	item := newSampleItem(1)
+	cmap.Store(item, "v4")
+	if _, ok := cmap.Load(newSampleItem(1)); ok {
+		t.Fatal("keys which aren't Hashable should be compared with ==, not Equals")
+	}
+	if v, ok := cmap.Load(item); !ok || v != "v4" {
+		t.Fatal("load of the same key should succeed")
+	}
+
+	if _, ok := cmap.Load([]int{1}); ok {
+		t.Fatal("load of an unhashable key should fail")
+	}
+	r := func() (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		cmap.Store([]int{1}, "v")
+		return
+	}()
+	if r != ErrIncomparable {
+		t.Fatal("store of an unhashable key should panic with ErrIncomparable")
+	}
+}
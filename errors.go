@@ -0,0 +1,33 @@
+package guc
+
+import "errors"
+
+var (
+	// the collection has no element
+	ErrEmpty = errors.New("guc: collection is empty")
+	// a bounded collection has no remaining capacity
+	ErrFull = errors.New("guc: collection is full")
+	// nil is not accepted as element, key or value
+	ErrNilElement = errors.New("guc: nil element")
+	// the collection or synchronizer is closed
+	ErrClosed = errors.New("guc: closed")
+	// a timed operation did not complete before its deadline
+	ErrTimeout = errors.New("guc: timeout")
+	// a party of a CyclicBarrier gave up or the barrier was reset
+	ErrBrokenBarrier = errors.New("guc: broken barrier")
+	// a method is called while the object isn't in a state for it
+	ErrIllegalState = errors.New("guc: illegal state")
+	// an argument is out of its valid range
+	ErrIllegalArgument = errors.New("guc: illegal argument")
+	// a collection was modified while being iterated by other means
+	// than the iterator itself
+	ErrConcurrentModification = errors.New("guc: concurrent modification")
+	// an index is out of the range of a list
+	ErrIndexOutOfRange = errors.New("guc: index out of range")
+	// a lock is released by an owner which doesn't hold it
+	ErrNotOwner = errors.New("guc: lock not held by owner")
+	// a stamp doesn't match the state of a StampedLock
+	ErrInvalidStamp = errors.New("guc: invalid stamp")
+	// the elements can't be compared with ==
+	ErrIncomparable = errors.New("guc: incomparable type")
+)
@@ -0,0 +1,88 @@
+package guc
+
+import (
+	"testing"
+)
+
+func TestTryRemoveHead(t *testing.T) {
+	queues := []interface {
+		Queue
+		TryAdd(i interface{}) error
+		TryRemoveHead() (interface{}, error)
+		TryElement() (interface{}, error)
+	}{
+		NewPriorityWithComparator(NaturalOrder()),
+		NewPriorityBlockingQueueWithComparator(NaturalOrder()),
+		NewConcurrentPriorityQueueWithShards(2, NaturalOrder()),
+	}
+	for _, q := range queues {
+		if _, err := q.TryRemoveHead(); err != ErrEmpty {
+			t.Fatal("TryRemoveHead of empty queue should return ErrEmpty")
+		}
+		if _, err := q.TryElement(); err != ErrEmpty {
+			t.Fatal("TryElement of empty queue should return ErrEmpty")
+		}
+		if q.TryAdd(nil) != ErrNilElement {
+			t.Fatal("TryAdd of nil should return ErrNilElement")
+		}
+		if q.TryAdd(1) != nil {
+			t.Fatal("TryAdd should succeed")
+		}
+		if i, err := q.TryElement(); i != 1 || err != nil {
+			t.Fatal("TryElement should return 1")
+		}
+		if i, err := q.TryRemoveHead(); i != 1 || err != nil {
+			t.Fatal("TryRemoveHead should return 1")
+		}
+		r := func() (err interface{}) {
+			defer func() {
+				err = recover()
+			}()
+			q.RemoveHead()
+			return
+		}()
+		if r != ErrEmpty {
+			t.Fatal("RemoveHead should panic with ErrEmpty")
+		}
+	}
+
+	b := NewBoundedPriorityBlockingQueue(1, NaturalOrder())
+	b.Add(1)
+	if b.TryAdd(2) != ErrFull {
+		t.Fatal("TryAdd of a full queue should return ErrFull")
+	}
+}
+
+func TestCheckedConstructors(t *testing.T) {
+	if _, err := NewConcurrentHashMapChecked(-1, 1); err != ErrIllegalArgument {
+		t.Fatal("negative capacity should return ErrIllegalArgument")
+	}
+	if m, err := NewConcurrentHashMapChecked(16, 1); m == nil || err != nil {
+		t.Fatal("map should be created")
+	}
+	if _, err := NewBoundedPriorityBlockingQueueChecked(0, nil, OverflowReject); err != ErrIllegalArgument {
+		t.Fatal("zero capacity should return ErrIllegalArgument")
+	}
+	if _, err := NewConcurrentPriorityQueueChecked(0, nil); err != ErrIllegalArgument {
+		t.Fatal("zero shards should return ErrIllegalArgument")
+	}
+}
+
+func TestConcurrentHashMap_TryStore(t *testing.T) {
+	cmap := NewConcurrentHashMap(16, 4)
+	if _, err := cmap.TryStore(nil, 1); err != ErrNilElement {
+		t.Fatal("nil key should return ErrNilElement")
+	}
+	if _, err := cmap.TryStore(1, nil); err != ErrNilElement {
+		t.Fatal("nil value should return ErrNilElement")
+	}
+	if _, err := cmap.TryStore([]int{1}, 1); err != ErrIncomparable {
+		t.Fatal("unhashable key should return ErrIncomparable")
+	}
+	if old, err := cmap.TryStore(1, "a"); old != nil || err != nil {
+		t.Fatal("store should succeed")
+	}
+	if old, err := cmap.TryStore(1, "b"); old != "a" || err != nil {
+		t.Fatal("store should return the previous value")
+	}
+}
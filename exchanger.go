@@ -0,0 +1,165 @@
+package guc
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+const (
+	// spins of a node in the arena before it goes back to the main slot
+	arenaSpins = 64
+	maxArena   = 32
+)
+
+// exchangeNode is a goroutine waiting for a partner in a slot
+type exchangeNode struct {
+	w    waiter
+	item interface{}
+	// written by the partner before it signals w
+	match interface{}
+}
+
+// fulfill gives item to the goroutine of this, return false if it has
+// given up waiting
+func (this *exchangeNode) fulfill(item interface{}) bool {
+	this.match = item
+	return this.w.signal()
+}
+
+func (this *exchangeNode) matched() bool {
+	return atomic.LoadInt32(&this.w.state) == waiterSignalled
+}
+
+type exchangeSlot struct {
+	// Volatile, type is *exchangeNode
+	node    unsafe.Pointer
+	padding [CacheLineSize - 8]byte
+}
+
+// Exchanger is a point where pairs of goroutines swap values. A goroutine
+// waits in the main slot until a partner takes its value and leaves its
+// own. Goroutines which collide on the main slot try to pair up in the
+// elimination arena, a set of slots picked at random, before coming back,
+// so the throughput scales under contention. Only the main slot blocks.
+// The zero value is ready to use
+type Exchanger struct {
+	slot exchangeSlot
+	// Volatile, type is *[]exchangeSlot, created on the first collision
+	arena unsafe.Pointer
+}
+
+func NewExchanger() *Exchanger {
+	return &Exchanger{}
+}
+
+// Exchange waits for a partner, gives it v and returns its value
+func (this *Exchanger) Exchange(v interface{}) interface{} {
+	r, _ := this.exchange(nil, v, time.Time{})
+	return r
+}
+
+// ExchangeWithTimeout is like Exchange, it returns ErrTimeout if no
+// partner comes within timeout
+func (this *Exchanger) ExchangeWithTimeout(v interface{}, timeout time.Duration) (interface{}, error) {
+	if r, ok := this.exchange(nil, v, time.Now().Add(timeout)); ok {
+		return r, nil
+	}
+	return nil, ErrTimeout
+}
+
+// ExchangeContext is like Exchange, it returns ctx.Err() if ctx is done
+// first
+func (this *Exchanger) ExchangeContext(ctx context.Context, v interface{}) (interface{}, error) {
+	if r, ok := this.exchange(ctx, v, time.Time{}); ok {
+		return r, nil
+	}
+	return nil, ctx.Err()
+}
+
+func (this *Exchanger) exchange(ctx context.Context, item interface{}, deadline time.Time) (interface{}, bool) {
+	if ctx != nil && ctx.Err() != nil {
+		return nil, false
+	}
+	inArena := false
+	for {
+		slot := &this.slot.node
+		if inArena {
+			slot = this.arenaSlot()
+		}
+		if q := (*exchangeNode)(atomic.LoadPointer(slot)); q != nil {
+			if atomic.CompareAndSwapPointer(slot, unsafe.Pointer(q), nil) && q.fulfill(item) {
+				return q.item, true
+			}
+			// lost the node to another goroutine or its owner gave up
+			inArena = !inArena
+			continue
+		}
+		node := &exchangeNode{item: item}
+		if !atomic.CompareAndSwapPointer(slot, nil, unsafe.Pointer(node)) {
+			inArena = !inArena
+			continue
+		}
+		if inArena {
+			if r, ok := this.awaitInArena(slot, node); ok {
+				return r, true
+			}
+			inArena = false
+			continue
+		}
+		for i := 0; SyncRuntimeCanSpin(i); i++ {
+			if node.matched() {
+				return node.match, true
+			}
+			SyncRuntimeDoSpin()
+		}
+		if node.w.await(ctx, deadline) {
+			return node.match, true
+		}
+		atomic.CompareAndSwapPointer(slot, unsafe.Pointer(node), nil)
+		return nil, false
+	}
+}
+
+// awaitInArena spins for a partner in an arena slot, return false if none
+// came and node is withdrawn
+func (this *Exchanger) awaitInArena(slot *unsafe.Pointer, node *exchangeNode) (interface{}, bool) {
+	for i := 0; i < arenaSpins; i++ {
+		if node.matched() {
+			return node.match, true
+		}
+		if SyncRuntimeCanSpin(i) {
+			SyncRuntimeDoSpin()
+		} else {
+			runtime.Gosched()
+		}
+	}
+	if atomic.CompareAndSwapPointer(slot, unsafe.Pointer(node), nil) {
+		return nil, false
+	}
+	// a partner took node, it signals soon
+	node.w.await(nil, time.Time{})
+	return node.match, true
+}
+
+func (this *Exchanger) arenaSlot() *unsafe.Pointer {
+	p := atomic.LoadPointer(&this.arena)
+	if p == nil {
+		n := runtime.GOMAXPROCS(0) / 2
+		if n < 1 {
+			n = 1
+		} else if n > maxArena {
+			n = maxArena
+		}
+		arena := make([]exchangeSlot, n)
+		if atomic.CompareAndSwapPointer(&this.arena, nil, unsafe.Pointer(&arena)) {
+			p = unsafe.Pointer(&arena)
+		} else {
+			p = atomic.LoadPointer(&this.arena)
+		}
+	}
+	arena := *(*[]exchangeSlot)(p)
+	return &arena[Fastrand()%uint32(len(arena))].node
+}
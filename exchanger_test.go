@@ -0,0 +1,67 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExchanger(t *testing.T) {
+	var e Exchanger
+	if _, err := e.ExchangeWithTimeout(1, 10*time.Millisecond); err != ErrTimeout {
+		t.Fatal("exchange without partner should time out")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if _, err := e.ExchangeContext(ctx, 1); err != context.Canceled {
+		t.Fatal("exchange should return context.Canceled")
+	}
+	got := make(chan interface{})
+	go func() {
+		got <- e.Exchange("a")
+	}()
+	if v, err := e.ExchangeWithTimeout("b", time.Minute); err != nil || v != "a" || <-got != "b" {
+		t.Fatal("partners should swap their values")
+	}
+}
+
+func TestExchanger_Concurrent(t *testing.T) {
+	e := NewExchanger()
+	n := 16
+	var wg sync.WaitGroup
+	var total int64
+	partners := make([][]int, n)
+	for g := 0; g < n; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			// a goroutine may be left without partner, stop by timeout
+			for atomic.AddInt64(&total, 1) < 3000 {
+				if v, err := e.ExchangeWithTimeout(g, 20*time.Millisecond); err == nil {
+					partners[g] = append(partners[g], v.(int))
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	// count how many times each pair met, from both sides
+	met := make(map[[2]int]int)
+	for g, ps := range partners {
+		for _, p := range ps {
+			if p == g {
+				t.Fatal("goroutine exchanged with itself")
+			}
+			met[[2]int{g, p}]++
+		}
+	}
+	for pair, cnt := range met {
+		if met[[2]int{pair[1], pair[0]}] != cnt {
+			t.Fatalf("pair %v met %d times from one side only", pair, cnt)
+		}
+	}
+}
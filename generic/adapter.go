@@ -0,0 +1,518 @@
+package generic
+
+import (
+	"iter"
+	"time"
+
+	"github.com/better-concurrent/guc"
+)
+
+// adapters between the typed and the untyped guc API, wrapping an adapter
+// again returns the original collection
+
+type typedWrapper interface {
+	untyped() guc.Collection
+}
+
+type untypedWrapper[E any] interface {
+	typed() Collection[E]
+}
+
+// unwrapUntyped returns the untyped collection behind an adapter,
+// i itself otherwise
+func unwrapUntyped(i interface{}) interface{} {
+	if w, ok := i.(typedWrapper); ok {
+		return w.untyped()
+	}
+	return i
+}
+
+// unwrapTyped returns the typed collection behind an adapter,
+// i itself otherwise
+func unwrapTyped[E any](i interface{}) interface{} {
+	if w, ok := i.(untypedWrapper[E]); ok {
+		return w.typed()
+	}
+	return i
+}
+
+// ====== untyped -> typed ========
+
+func FromUntypedComparator[E any](c guc.Comparator) Comparator[E] {
+	return func(a, b E) int {
+		return c.Compare(a, b)
+	}
+}
+
+func FromUntypedIterator[E any](iter guc.Iterator) Iterator[E] {
+	if u, ok := iter.(*untypedIterator[E]); ok {
+		return u.iter
+	}
+	return &typedIterator[E]{iter: iter}
+}
+
+func FromUntypedCollection[E any](coll guc.Collection) Collection[E] {
+	if u, ok := coll.(untypedWrapper[E]); ok {
+		return u.typed()
+	}
+	return &typedCollection[E]{coll: coll}
+}
+
+func FromUntypedQueue[E any](queue guc.Queue) Queue[E] {
+	if u, ok := queue.(untypedWrapper[E]); ok {
+		if r, ok := u.typed().(Queue[E]); ok {
+			return r
+		}
+	}
+	return newTypedQueue[E](queue)
+}
+
+func FromUntypedBlockingQueue[E any](queue guc.BlockingQueue) BlockingQueue[E] {
+	if u, ok := queue.(untypedWrapper[E]); ok {
+		if r, ok := u.typed().(BlockingQueue[E]); ok {
+			return r
+		}
+	}
+	return newTypedBlockingQueue[E](queue)
+}
+
+type typedIterator[E any] struct {
+	iter guc.Iterator
+}
+
+func (this *typedIterator[E]) HasNext() bool {
+	return this.iter.HasNext()
+}
+
+func (this *typedIterator[E]) Next() E {
+	return cast[E](this.iter.Next())
+}
+
+func (this *typedIterator[E]) Remove() {
+	this.iter.Remove()
+}
+
+func (this *typedIterator[E]) ForEachRemaining(consumer func(e E)) {
+	this.iter.ForEachRemaining(func(i interface{}) {
+		consumer(cast[E](i))
+	})
+}
+
+type typedCollection[E any] struct {
+	coll guc.Collection
+}
+
+func (this *typedCollection[E]) untyped() guc.Collection {
+	return this.coll
+}
+
+func (this *typedCollection[E]) Iterator() Iterator[E] {
+	return FromUntypedIterator[E](this.coll.Iterator())
+}
+
+func (this *typedCollection[E]) ForEach(consumer func(e E)) {
+	this.coll.ForEach(func(i interface{}) {
+		consumer(cast[E](i))
+	})
+}
+
+func (this *typedCollection[E]) All() iter.Seq[E] {
+	return func(yield func(E) bool) {
+		for v := range this.coll.All() {
+			if !yield(cast[E](v)) {
+				return
+			}
+		}
+	}
+}
+
+func (this *typedCollection[E]) Size() int {
+	return this.coll.Size()
+}
+
+func (this *typedCollection[E]) IsEmpty() bool {
+	return this.coll.IsEmpty()
+}
+
+func (this *typedCollection[E]) Contains(e E) bool {
+	return this.coll.Contains(e)
+}
+
+func (this *typedCollection[E]) ToArray() []E {
+	return castArray[E](this.coll.ToArray())
+}
+
+func (this *typedCollection[E]) FillArray(arr []E) []E {
+	data := this.coll.ToArray()
+	if len(arr) < len(data) {
+		arr = make([]E, len(data))
+	}
+	for i, v := range data {
+		arr[i] = cast[E](v)
+	}
+	return arr[:len(data)]
+}
+
+func (this *typedCollection[E]) Add(e E) bool {
+	return this.coll.Add(e)
+}
+
+func (this *typedCollection[E]) Remove(e E) bool {
+	return this.coll.Remove(e)
+}
+
+func (this *typedCollection[E]) ContainsAll(coll Collection[E]) bool {
+	return this.coll.ContainsAll(ToUntypedCollection(coll))
+}
+
+func (this *typedCollection[E]) AddAll(coll Collection[E]) bool {
+	return this.coll.AddAll(ToUntypedCollection(coll))
+}
+
+func (this *typedCollection[E]) RemoveAll(coll Collection[E]) bool {
+	return this.coll.RemoveAll(ToUntypedCollection(coll))
+}
+
+func (this *typedCollection[E]) RemoveIf(predicate func(e E) bool) bool {
+	return this.coll.RemoveIf(func(i interface{}) bool {
+		return predicate(cast[E](i))
+	})
+}
+
+func (this *typedCollection[E]) RetainAll(coll Collection[E]) bool {
+	return this.coll.RetainAll(ToUntypedCollection(coll))
+}
+
+func (this *typedCollection[E]) Clear() {
+	this.coll.Clear()
+}
+
+func (this *typedCollection[E]) Equals(i interface{}) bool {
+	return this.coll.Equals(unwrapUntyped(i))
+}
+
+func (this *typedCollection[E]) HashCode() int {
+	return this.coll.HashCode()
+}
+
+type typedQueue[E any] struct {
+	typedCollection[E]
+	queue guc.Queue
+}
+
+func newTypedQueue[E any](queue guc.Queue) *typedQueue[E] {
+	return &typedQueue[E]{typedCollection: typedCollection[E]{coll: queue}, queue: queue}
+}
+
+func (this *typedQueue[E]) Offer(e E) bool {
+	return this.queue.Offer(e)
+}
+
+func (this *typedQueue[E]) RemoveHead() E {
+	return cast[E](this.queue.RemoveHead())
+}
+
+func (this *typedQueue[E]) Poll() (E, bool) {
+	i := this.queue.Poll()
+	return cast[E](i), i != nil
+}
+
+func (this *typedQueue[E]) Element() E {
+	return cast[E](this.queue.Element())
+}
+
+func (this *typedQueue[E]) Peek() (E, bool) {
+	i := this.queue.Peek()
+	return cast[E](i), i != nil
+}
+
+type typedBlockingQueue[E any] struct {
+	typedQueue[E]
+	blockingQueue guc.BlockingQueue
+}
+
+func newTypedBlockingQueue[E any](queue guc.BlockingQueue) *typedBlockingQueue[E] {
+	return &typedBlockingQueue[E]{typedQueue: *newTypedQueue[E](queue), blockingQueue: queue}
+}
+
+func (this *typedBlockingQueue[E]) Put(e E) {
+	this.blockingQueue.Put(e)
+}
+
+func (this *typedBlockingQueue[E]) OfferWithTimeout(e E, t time.Duration) bool {
+	return this.blockingQueue.OfferWithTimeout(e, t)
+}
+
+func (this *typedBlockingQueue[E]) Take() E {
+	return cast[E](this.blockingQueue.Take())
+}
+
+func (this *typedBlockingQueue[E]) PollWithTimeout(t time.Duration) (E, bool) {
+	i := this.blockingQueue.PollWithTimeout(t)
+	return cast[E](i), i != nil
+}
+
+func (this *typedBlockingQueue[E]) RemainingCapacity() int {
+	return this.blockingQueue.RemainingCapacity()
+}
+
+func (this *typedBlockingQueue[E]) DrainTo(coll Collection[E]) int {
+	return this.blockingQueue.DrainTo(ToUntypedCollection(coll))
+}
+
+func (this *typedBlockingQueue[E]) DrainToWithLimit(coll Collection[E], max int) int {
+	return this.blockingQueue.DrainToWithLimit(ToUntypedCollection(coll), max)
+}
+
+// ====== typed -> untyped ========
+
+type untypedComparator[E any] struct {
+	c Comparator[E]
+}
+
+func (this untypedComparator[E]) Compare(o1, o2 interface{}) int {
+	return this.c(cast[E](o1), cast[E](o2))
+}
+
+func ToUntypedComparator[E any](c Comparator[E]) guc.Comparator {
+	if c == nil {
+		return nil
+	}
+	return untypedComparator[E]{c: c}
+}
+
+func ToUntypedIterator[E any](iter Iterator[E]) guc.Iterator {
+	if t, ok := iter.(*typedIterator[E]); ok {
+		return t.iter
+	}
+	return &untypedIterator[E]{iter: iter}
+}
+
+func ToUntypedCollection[E any](coll Collection[E]) guc.Collection {
+	if t, ok := coll.(typedWrapper); ok {
+		return t.untyped()
+	}
+	return &untypedCollection[E]{coll: coll}
+}
+
+func ToUntypedQueue[E any](queue Queue[E]) guc.Queue {
+	if t, ok := queue.(typedWrapper); ok {
+		if r, ok := t.untyped().(guc.Queue); ok {
+			return r
+		}
+	}
+	return newUntypedQueue[E](queue)
+}
+
+func ToUntypedBlockingQueue[E any](queue BlockingQueue[E]) guc.BlockingQueue {
+	if t, ok := queue.(typedWrapper); ok {
+		if r, ok := t.untyped().(guc.BlockingQueue); ok {
+			return r
+		}
+	}
+	return newUntypedBlockingQueue[E](queue)
+}
+
+type untypedIterator[E any] struct {
+	iter Iterator[E]
+}
+
+func (this *untypedIterator[E]) HasNext() bool {
+	return this.iter.HasNext()
+}
+
+func (this *untypedIterator[E]) Next() interface{} {
+	return this.iter.Next()
+}
+
+func (this *untypedIterator[E]) Remove() {
+	this.iter.Remove()
+}
+
+func (this *untypedIterator[E]) ForEachRemaining(consumer func(i interface{})) {
+	this.iter.ForEachRemaining(func(e E) {
+		consumer(e)
+	})
+}
+
+type untypedCollection[E any] struct {
+	coll Collection[E]
+}
+
+func (this *untypedCollection[E]) typed() Collection[E] {
+	return this.coll
+}
+
+func (this *untypedCollection[E]) Iterator() guc.Iterator {
+	return ToUntypedIterator(this.coll.Iterator())
+}
+
+func (this *untypedCollection[E]) ForEach(consumer func(i interface{})) {
+	this.coll.ForEach(func(e E) {
+		consumer(e)
+	})
+}
+
+func (this *untypedCollection[E]) All() iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for v := range this.coll.All() {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func (this *untypedCollection[E]) Size() int {
+	return this.coll.Size()
+}
+
+func (this *untypedCollection[E]) IsEmpty() bool {
+	return this.coll.IsEmpty()
+}
+
+func (this *untypedCollection[E]) Contains(i interface{}) bool {
+	e, ok := i.(E)
+	return ok && this.coll.Contains(e)
+}
+
+func (this *untypedCollection[E]) ToArray() []interface{} {
+	arr := this.coll.ToArray()
+	result := make([]interface{}, len(arr))
+	for i, v := range arr {
+		result[i] = v
+	}
+	return result
+}
+
+func (this *untypedCollection[E]) FillArray(arr []interface{}) []interface{} {
+	data := this.coll.ToArray()
+	if len(arr) < len(data) {
+		arr = make([]interface{}, len(data))
+	}
+	for i, v := range data {
+		arr[i] = v
+	}
+	return arr[:len(data)]
+}
+
+func (this *untypedCollection[E]) Add(i interface{}) bool {
+	return this.coll.Add(cast[E](i))
+}
+
+func (this *untypedCollection[E]) Remove(i interface{}) bool {
+	e, ok := i.(E)
+	return ok && this.coll.Remove(e)
+}
+
+func (this *untypedCollection[E]) ContainsAll(coll guc.Collection) bool {
+	return this.coll.ContainsAll(FromUntypedCollection[E](coll))
+}
+
+func (this *untypedCollection[E]) AddAll(coll guc.Collection) bool {
+	return this.coll.AddAll(FromUntypedCollection[E](coll))
+}
+
+func (this *untypedCollection[E]) RemoveAll(coll guc.Collection) bool {
+	return this.coll.RemoveAll(FromUntypedCollection[E](coll))
+}
+
+func (this *untypedCollection[E]) RemoveIf(predicate func(i interface{}) bool) bool {
+	return this.coll.RemoveIf(func(e E) bool {
+		return predicate(e)
+	})
+}
+
+func (this *untypedCollection[E]) RetainAll(coll guc.Collection) bool {
+	return this.coll.RetainAll(FromUntypedCollection[E](coll))
+}
+
+func (this *untypedCollection[E]) Clear() {
+	this.coll.Clear()
+}
+
+func (this *untypedCollection[E]) Equals(i interface{}) bool {
+	return this.coll.Equals(unwrapTyped[E](i))
+}
+
+func (this *untypedCollection[E]) HashCode() int {
+	return this.coll.HashCode()
+}
+
+type untypedQueue[E any] struct {
+	untypedCollection[E]
+	queue Queue[E]
+}
+
+func newUntypedQueue[E any](queue Queue[E]) *untypedQueue[E] {
+	return &untypedQueue[E]{untypedCollection: untypedCollection[E]{coll: queue}, queue: queue}
+}
+
+func (this *untypedQueue[E]) Offer(i interface{}) bool {
+	return this.queue.Offer(cast[E](i))
+}
+
+func (this *untypedQueue[E]) RemoveHead() interface{} {
+	return this.queue.RemoveHead()
+}
+
+func (this *untypedQueue[E]) Poll() interface{} {
+	e, ok := this.queue.Poll()
+	if !ok {
+		return nil
+	}
+	return e
+}
+
+func (this *untypedQueue[E]) Element() interface{} {
+	return this.queue.Element()
+}
+
+func (this *untypedQueue[E]) Peek() interface{} {
+	e, ok := this.queue.Peek()
+	if !ok {
+		return nil
+	}
+	return e
+}
+
+type untypedBlockingQueue[E any] struct {
+	untypedQueue[E]
+	blockingQueue BlockingQueue[E]
+}
+
+func newUntypedBlockingQueue[E any](queue BlockingQueue[E]) *untypedBlockingQueue[E] {
+	return &untypedBlockingQueue[E]{untypedQueue: *newUntypedQueue[E](queue), blockingQueue: queue}
+}
+
+func (this *untypedBlockingQueue[E]) Put(i interface{}) {
+	this.blockingQueue.Put(cast[E](i))
+}
+
+func (this *untypedBlockingQueue[E]) OfferWithTimeout(i interface{}, t time.Duration) bool {
+	return this.blockingQueue.OfferWithTimeout(cast[E](i), t)
+}
+
+func (this *untypedBlockingQueue[E]) Take() interface{} {
+	return this.blockingQueue.Take()
+}
+
+func (this *untypedBlockingQueue[E]) PollWithTimeout(t time.Duration) interface{} {
+	e, ok := this.blockingQueue.PollWithTimeout(t)
+	if !ok {
+		return nil
+	}
+	return e
+}
+
+func (this *untypedBlockingQueue[E]) RemainingCapacity() int {
+	return this.blockingQueue.RemainingCapacity()
+}
+
+func (this *untypedBlockingQueue[E]) DrainTo(coll guc.Collection) int {
+	return this.blockingQueue.DrainTo(FromUntypedCollection[E](coll))
+}
+
+func (this *untypedBlockingQueue[E]) DrainToWithLimit(coll guc.Collection, max int) int {
+	return this.blockingQueue.DrainToWithLimit(FromUntypedCollection[E](coll), max)
+}
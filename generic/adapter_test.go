@@ -0,0 +1,86 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+type sampleItem struct {
+	Value int
+}
+
+func (this *sampleItem) Equals(i interface{}) bool {
+	dst, ok := i.(*sampleItem)
+	return ok && this.Value == dst.Value
+}
+
+func (this *sampleItem) CompareTo(i interface{}) int {
+	return this.Value - i.(*sampleItem).Value
+}
+
+func TestFromUntypedQueue(t *testing.T) {
+	u := guc.NewPriority()
+	u.Add(&sampleItem{Value: 2})
+	u.Add(&sampleItem{Value: 1})
+	q := FromUntypedQueue[*sampleItem](u)
+	if h, ok := q.Peek(); !ok || h.Value != 1 {
+		t.Fatal("head should be value 1")
+	}
+	if !q.Contains(&sampleItem{Value: 2}) {
+		t.Fatal("queue should contain value 2")
+	}
+	arr := q.ToArray()
+	if len(arr) != 2 {
+		t.Fatal("array size should be 2")
+	}
+	if ToUntypedQueue(q) != guc.Queue(u) {
+		t.Fatal("unwrapping should return the original queue")
+	}
+	if !q.Equals(u) {
+		t.Fatal("adapter should be equal to the original queue")
+	}
+	q.Poll()
+	q.Poll()
+	if _, ok := q.Poll(); ok {
+		t.Fatal("poll of empty queue should return false")
+	}
+}
+
+func TestToUntypedBlockingQueue(t *testing.T) {
+	p := NewPriorityBlockingQueue(intComparator)
+	u := ToUntypedBlockingQueue[int](p)
+	if u != guc.BlockingQueue(p.Untyped()) {
+		t.Fatal("unwrapping should return the underlying queue")
+	}
+
+	c := newUntypedBlockingQueue[int](p)
+	c.Put(2)
+	c.Add(1)
+	if c.Peek() != 1 || c.Size() != 2 {
+		t.Fatal("head should be 1")
+	}
+	if c.Contains("a") {
+		t.Fatal("should not contain an element of another type")
+	}
+	if FromUntypedBlockingQueue[int](c) != BlockingQueue[int](p) {
+		t.Fatal("unwrapping should return the typed queue")
+	}
+	if c.Take() != 1 || c.Poll() != 2 || c.Poll() != nil {
+		t.Fatal("poll order error")
+	}
+}
+
+func TestComparatorAdapters(t *testing.T) {
+	u := ToUntypedComparator[int](intComparator)
+	if u.Compare(1, 2) >= 0 {
+		t.Fatal("1 should be less than 2")
+	}
+	c := FromUntypedComparator[int](u)
+	if c(3, 2) <= 0 {
+		t.Fatal("3 should be greater than 2")
+	}
+	if ToUntypedComparator[int](nil) != nil {
+		t.Fatal("nil comparator should stay nil")
+	}
+}
@@ -0,0 +1,95 @@
+// Package generic provides type-parameterized versions of the guc
+// collection interfaces and queues, plus adapters to the untyped guc API.
+package generic
+
+import (
+	"iter"
+	"time"
+)
+
+// this function type compares two elements, it returns a negative integer,
+// zero, or a positive integer as a is less than, equal to, or greater than b
+type Comparator[E any] func(a, b E) int
+
+type Iterator[E any] interface {
+	HasNext() bool
+	Next() E
+	Remove()
+	ForEachRemaining(consumer func(e E))
+}
+
+type Iterable[E any] interface {
+	Iterator() Iterator[E]
+	ForEach(consumer func(e E))
+	// for range-over-func loops
+	All() iter.Seq[E]
+}
+
+type Collection[E any] interface {
+	Iterable[E]
+	Size() int
+	IsEmpty() bool
+	Contains(e E) bool
+	ToArray() []E
+	FillArray(arr []E) []E
+
+	Add(e E) bool
+	Remove(e E) bool
+	ContainsAll(coll Collection[E]) bool
+	AddAll(coll Collection[E]) bool
+	RemoveAll(coll Collection[E]) bool
+	RemoveIf(predicate func(e E) bool) bool
+	RetainAll(coll Collection[E]) bool
+	Clear()
+	Equals(i interface{}) bool
+	HashCode() int
+}
+
+type Queue[E any] interface {
+	Collection[E]
+
+	Offer(e E) bool
+	// retrieve and remove head
+	// panic if empty
+	RemoveHead() E
+	// retrieve and remove head
+	// return false if empty
+	Poll() (E, bool)
+	// retrieve head of the queue
+	// panic if empty
+	Element() E
+	// retrieve head of the queue
+	// return false if empty
+	Peek() (E, bool)
+}
+
+type BlockingQueue[E any] interface {
+	Queue[E]
+
+	Put(e E)
+	OfferWithTimeout(e E, t time.Duration) bool
+	Take() E
+	PollWithTimeout(t time.Duration) (E, bool)
+	RemainingCapacity() int
+	DrainTo(coll Collection[E]) int
+	DrainToWithLimit(coll Collection[E], max int) int
+}
+
+
+// cast converts an untyped element, nil becomes the zero value of E
+func cast[E any](i interface{}) E {
+	if i == nil {
+		var zero E
+		return zero
+	}
+	return i.(E)
+}
+
+// castArray converts untyped elements, see cast
+func castArray[E any](arr []interface{}) []E {
+	result := make([]E, len(arr))
+	for i, v := range arr {
+		result[i] = cast[E](v)
+	}
+	return result
+}
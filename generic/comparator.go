@@ -0,0 +1,35 @@
+package generic
+
+import (
+	"cmp"
+)
+
+// NaturalOrder compares values of Go ordered types by their natural order
+func NaturalOrder[E cmp.Ordered]() Comparator[E] {
+	return cmp.Compare[E]
+}
+
+// Reversed imposes the reverse ordering of c
+func Reversed[E any](c Comparator[E]) Comparator[E] {
+	return func(a, b E) int {
+		return c(b, a)
+	}
+}
+
+// ThenComparing orders by c1 first, then by c2 for the values c1 considers equal
+func ThenComparing[E any](c1, c2 Comparator[E]) Comparator[E] {
+	return func(a, b E) int {
+		r := c1(a, b)
+		if r != 0 {
+			return r
+		}
+		return c2(a, b)
+	}
+}
+
+// Comparing orders values by the natural order of the keys extracted by keyFn
+func Comparing[E any, K cmp.Ordered](keyFn func(e E) K) Comparator[E] {
+	return func(a, b E) int {
+		return cmp.Compare(keyFn(a), keyFn(b))
+	}
+}
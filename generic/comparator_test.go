@@ -0,0 +1,30 @@
+package generic
+
+import (
+	"testing"
+)
+
+type sampleJob struct {
+	priority int
+	name     string
+}
+
+func TestComparators(t *testing.T) {
+	p := NewPriorityQueue(Reversed(NaturalOrder[string]()))
+	p.Add("a")
+	p.Add("c")
+	p.Add("b")
+	if v, _ := p.Poll(); v != "c" {
+		t.Fatal("should poll in reversed order")
+	}
+
+	c := ThenComparing(
+		Comparing(func(j sampleJob) int { return j.priority }),
+		Comparing(func(j sampleJob) string { return j.name }))
+	if c(sampleJob{0, "b"}, sampleJob{1, "a"}) >= 0 {
+		t.Fatal("should order by priority first")
+	}
+	if c(sampleJob{1, "b"}, sampleJob{1, "a"}) <= 0 {
+		t.Fatal("should order by name for equal priority")
+	}
+}
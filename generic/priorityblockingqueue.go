@@ -0,0 +1,43 @@
+package generic
+
+import (
+	"github.com/better-concurrent/guc"
+)
+
+var _ BlockingQueue[int] = new(PriorityBlockingQueue[int])
+
+// PriorityBlockingQueue is a typed view over guc.PriorityBlockingQueue,
+// elements are compared by its equality, see guc.PriorityBlockingQueue.SetEquality
+type PriorityBlockingQueue[E any] struct {
+	typedBlockingQueue[E]
+	queue *guc.PriorityBlockingQueue
+}
+
+// NewPriorityBlockingQueue creates an unbounded queue ordered by comparator,
+// comparator may be nil if E implements guc.Comparable
+func NewPriorityBlockingQueue[E any](comparator Comparator[E]) *PriorityBlockingQueue[E] {
+	return wrapPriorityBlockingQueue[E](
+		guc.NewPriorityBlockingQueueWithComparator(ToUntypedComparator(comparator)))
+}
+
+// NewBoundedPriorityBlockingQueue creates a queue holding at most capacity elements,
+// see guc.NewBoundedPriorityBlockingQueue
+func NewBoundedPriorityBlockingQueue[E any](capacity int, comparator Comparator[E]) *PriorityBlockingQueue[E] {
+	return wrapPriorityBlockingQueue[E](
+		guc.NewBoundedPriorityBlockingQueue(capacity, ToUntypedComparator(comparator)))
+}
+
+func wrapPriorityBlockingQueue[E any](queue *guc.PriorityBlockingQueue) *PriorityBlockingQueue[E] {
+	return &PriorityBlockingQueue[E]{typedBlockingQueue: *newTypedBlockingQueue[E](queue), queue: queue}
+}
+
+// Untyped returns the underlying queue, for the features only available
+// on the untyped API
+func (this *PriorityBlockingQueue[E]) Untyped() *guc.PriorityBlockingQueue {
+	return this.queue
+}
+
+// ToSortedArray returns all elements in priority order
+func (this *PriorityBlockingQueue[E]) ToSortedArray() []E {
+	return castArray[E](this.queue.ToSortedArray())
+}
@@ -0,0 +1,43 @@
+package generic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriorityBlockingQueue_Take(t *testing.T) {
+	p := NewPriorityBlockingQueue(intComparator)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		p.Put(3)
+		p.Put(1)
+	}()
+	if v := p.Take(); v != 3 && v != 1 {
+		t.Fatal("take should return an offered value")
+	}
+	if !p.Contains(1) && !p.Contains(3) {
+		t.Fatal("queue should contain the other value")
+	}
+}
+
+func TestBoundedPriorityBlockingQueue(t *testing.T) {
+	p := NewBoundedPriorityBlockingQueue(2, intComparator)
+	p.Put(2)
+	p.Put(1)
+	if p.OfferWithTimeout(3, 10*time.Millisecond) {
+		t.Fatal("offer should time out when full")
+	}
+	if p.RemainingCapacity() != 0 {
+		t.Fatal("remaining capacity should be 0")
+	}
+	d := NewPriorityQueue(intComparator)
+	if p.DrainTo(d) != 2 {
+		t.Fatal("drain result should be 2")
+	}
+	if v, _ := d.Poll(); v != 1 {
+		t.Fatal("drained head should be 1")
+	}
+	if !p.IsEmpty() || p.Remove(1) {
+		t.Fatal("queue should be empty")
+	}
+}
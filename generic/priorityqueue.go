@@ -0,0 +1,44 @@
+package generic
+
+import (
+	"github.com/better-concurrent/guc"
+)
+
+var _ Queue[int] = new(PriorityQueue[int])
+
+// PriorityQueue is a typed view over guc.PriorityQueue, elements are
+// compared by its equality, see guc.PriorityQueue.SetEquality
+type PriorityQueue[E any] struct {
+	typedQueue[E]
+	queue *guc.PriorityQueue
+}
+
+// NewPriorityQueue creates an empty queue ordered by comparator,
+// comparator may be nil if E implements guc.Comparable
+func NewPriorityQueue[E any](comparator Comparator[E]) *PriorityQueue[E] {
+	return wrapPriorityQueue[E](guc.NewPriorityWithComparator(ToUntypedComparator(comparator)))
+}
+
+// NewPriorityQueueFromSlice creates a queue holding a copy of arr, see guc.NewPriorityFromSlice
+func NewPriorityQueueFromSlice[E any](arr []E, comparator Comparator[E]) *PriorityQueue[E] {
+	data := make([]interface{}, len(arr))
+	for i, v := range arr {
+		data[i] = v
+	}
+	return wrapPriorityQueue[E](guc.NewPriorityFromSlice(data, ToUntypedComparator(comparator)))
+}
+
+func wrapPriorityQueue[E any](queue *guc.PriorityQueue) *PriorityQueue[E] {
+	return &PriorityQueue[E]{typedQueue: *newTypedQueue[E](queue), queue: queue}
+}
+
+// Untyped returns the underlying queue, for the features only available
+// on the untyped API
+func (this *PriorityQueue[E]) Untyped() *guc.PriorityQueue {
+	return this.queue
+}
+
+// ToSortedArray returns all elements in priority order
+func (this *PriorityQueue[E]) ToSortedArray() []E {
+	return castArray[E](this.queue.ToSortedArray())
+}
@@ -0,0 +1,124 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+func intComparator(a, b int) int {
+	return a - b
+}
+
+func newPreparedPriorityQueue() *PriorityQueue[int] {
+	p := NewPriorityQueue(intComparator)
+	for _, v := range []int{6, 8, 3, 6, 33, 7, 2} {
+		p.Add(v)
+	}
+	return p
+}
+
+func TestPriorityQueue_Poll(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	if p.Size() != 7 {
+		t.Fatal("queue size should be 7")
+	}
+	if h, ok := p.Peek(); !ok || h != 2 {
+		t.Fatal("head should be 2")
+	}
+	prev := -1
+	for !p.IsEmpty() {
+		v, ok := p.Poll()
+		if !ok || v < prev {
+			t.Fatal("poll should follow priority order")
+		}
+		prev = v
+	}
+	if _, ok := p.Poll(); ok {
+		t.Fatal("poll of empty queue should return false")
+	}
+}
+
+func TestPriorityQueue_ContainsRemove(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	if !p.Contains(33) || p.Contains(100) {
+		t.Fatal("contains result error")
+	}
+	if !p.Remove(6) || !p.Remove(6) || p.Remove(6) {
+		t.Fatal("remove result error")
+	}
+	c := NewPriorityQueue(intComparator)
+	c.Add(3)
+	c.Add(100)
+	if p.ContainsAll(c) {
+		t.Fatal("should not contains all")
+	}
+	if !p.RemoveAll(c) {
+		t.Fatal("remove all should return true")
+	}
+	if p.Size() != 4 {
+		t.Fatal("queue size should be 4")
+	}
+	c.Remove(100)
+	c.Add(33)
+	if !p.RetainAll(c) || p.Size() != 1 {
+		t.Fatal("only 33 should be retained")
+	}
+}
+
+func TestPriorityQueue_Equality(t *testing.T) {
+	parity := guc.EqualityFunc(func(o1, o2 interface{}) (bool, error) {
+		return o1.(int)%2 == o2.(int)%2, nil
+	})
+	p := newPreparedPriorityQueue()
+	p.Untyped().SetEquality(parity)
+	if !p.Contains(5) || !p.Remove(5) || p.Size() != 6 {
+		t.Fatal("equality of the untyped queue should be used")
+	}
+	b := NewPriorityBlockingQueue(intComparator)
+	b.Add(2)
+	b.Untyped().SetEquality(parity)
+	if !b.Contains(4) || !b.Remove(4) || !b.IsEmpty() {
+		t.Fatal("equality of the untyped blocking queue should be used")
+	}
+}
+
+func TestPriorityQueue_ToArray(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	if len(p.ToArray()) != 7 {
+		t.Fatal("array size must be 7")
+	}
+	sorted := p.ToSortedArray()
+	expected := []int{2, 3, 6, 6, 7, 8, 33}
+	for i, v := range sorted {
+		if v != expected[i] {
+			t.Fatal("array should be sorted")
+		}
+	}
+	q := NewPriorityQueueFromSlice([]int{5, 1, 4}, intComparator)
+	if h := q.Element(); h != 1 {
+		t.Fatal("head should be 1")
+	}
+	sum := 0
+	q.ForEach(func(e int) {
+		sum += e
+	})
+	if sum != 10 {
+		t.Fatal("sum should be 10")
+	}
+}
+
+func TestPriorityQueue_Equals(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	if !p.Equals(p) || !p.Equals(p.Untyped()) {
+		t.Fatal("queue should be equals to itself")
+	}
+	other := newPreparedPriorityQueue()
+	if !p.Equals(other) || p.HashCode() != other.HashCode() {
+		t.Fatal("queue should be equals to a queue with the same elements")
+	}
+	other.Poll()
+	if p.Equals(other) {
+		t.Fatal("queue should not be equals to a queue with other elements")
+	}
+}
@@ -0,0 +1,178 @@
+package guctest
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/better-concurrent/guc"
+)
+
+// how long the suite waits to decide that a call blocks
+const blockDelay = 50 * time.Millisecond
+
+// TestBlockingQueue runs the Queue suite including the concurrent tests,
+// and the BlockingQueue suite. newQueue must return an empty queue,
+// bounded to opts.Capacity if it is set
+func TestBlockingQueue(t *testing.T, newQueue func() guc.BlockingQueue, opts *Options) {
+	o := *defaultOptions(opts)
+	o.Concurrent = true
+	opts = &o
+	n := opts.size()
+
+	TestQueue(t, func() guc.Queue { return newQueue() }, opts)
+
+	opts.run(t, "PutTake", func(t *testing.T) {
+		q := newQueue()
+		q.Put(opts.element(0))
+		if q.Size() != 1 || q.Take() != opts.element(0) || !q.IsEmpty() {
+			t.Fatal("take should return the element put")
+		}
+	})
+
+	opts.run(t, "TakeBlocks", func(t *testing.T) {
+		q := newQueue()
+		ch := make(chan interface{})
+		go func() {
+			ch <- q.Take()
+		}()
+		select {
+		case <-ch:
+			t.Fatal("take of an empty queue should block")
+		case <-time.After(blockDelay):
+		}
+		q.Put(opts.element(0))
+		select {
+		case i := <-ch:
+			if i != opts.element(0) {
+				t.Fatal("take should return the element put")
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("take should be woken up by put")
+		}
+	})
+
+	opts.run(t, "PollWithTimeout", func(t *testing.T) {
+		q := newQueue()
+		begin := time.Now()
+		if q.PollWithTimeout(blockDelay) != nil {
+			t.Fatal("poll of an empty queue should time out")
+		}
+		if time.Since(begin) < blockDelay {
+			t.Fatal("poll returned before the timeout")
+		}
+		go func() {
+			time.Sleep(blockDelay)
+			q.Put(opts.element(0))
+		}()
+		if q.PollWithTimeout(10*time.Second) != opts.element(0) {
+			t.Fatal("poll should be woken up by put")
+		}
+	})
+
+	opts.run(t, "RemainingCapacity", func(t *testing.T) {
+		q := newQueue()
+		if opts.Capacity == 0 {
+			fill(t, q, opts, 0, n)
+			if q.RemainingCapacity() != math.MaxInt32 {
+				t.Fatal("remaining capacity of an unbounded queue should be MaxInt32")
+			}
+			return
+		}
+		if q.RemainingCapacity() != opts.Capacity {
+			t.Fatal("remaining capacity of an empty queue should be the capacity")
+		}
+		fill(t, q, opts, 0, n)
+		if q.RemainingCapacity() != opts.Capacity-n {
+			t.Fatalf("remaining capacity is %d, expected %d", q.RemainingCapacity(), opts.Capacity-n)
+		}
+	})
+
+	if opts.Capacity > 0 {
+		opts.run(t, "Full", func(t *testing.T) {
+			q := newQueue()
+			fill(t, q, opts, 0, opts.Capacity)
+			full := opts.element(opts.Capacity)
+			if q.Offer(full) || q.Add(full) || q.Size() != opts.Capacity {
+				t.Fatal("offer to a full queue should return false")
+			}
+			begin := time.Now()
+			if q.OfferWithTimeout(full, blockDelay) {
+				t.Fatal("offer to a full queue should time out")
+			}
+			if time.Since(begin) < blockDelay {
+				t.Fatal("offer returned before the timeout")
+			}
+			done := make(chan struct{})
+			go func() {
+				q.Put(full)
+				close(done)
+			}()
+			select {
+			case <-done:
+				t.Fatal("put to a full queue should block")
+			case <-time.After(blockDelay):
+			}
+			q.Take()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("put should be woken up by take")
+			}
+			if !q.Contains(full) || q.Size() != opts.Capacity {
+				t.Fatal("put element should be queued")
+			}
+		})
+	}
+
+	opts.run(t, "DrainTo", func(t *testing.T) {
+		q := newQueue()
+		fill(t, q, opts, 0, n)
+		c := newSliceCollection()
+		if q.DrainToWithLimit(c, 3) != 3 || c.Size() != 3 || q.Size() != n-3 {
+			t.Fatal("drain to with limit should move 3 elements")
+		}
+		if q.DrainTo(c) != n-3 || !q.IsEmpty() {
+			t.Fatal("drain to should move all elements")
+		}
+		expectElements(t, c.data, opts, 0, n)
+		if q.DrainTo(c) != 0 {
+			t.Fatal("drain of an empty queue should move nothing")
+		}
+	})
+
+	opts.run(t, "ConcurrentPutTake", func(t *testing.T) {
+		q := newQueue()
+		gc, perG := 4, 2000
+		var wg sync.WaitGroup
+		for g := 0; g < gc; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := g * perG; i < (g+1)*perG; i++ {
+					q.Put(opts.element(i))
+				}
+			}(g)
+		}
+		results := make(chan []interface{}, gc)
+		for g := 0; g < gc; g++ {
+			go func() {
+				taken := make([]interface{}, 0, perG)
+				for i := 0; i < perG; i++ {
+					taken = append(taken, q.Take())
+				}
+				results <- taken
+			}()
+		}
+		taken := make([]interface{}, 0, gc*perG)
+		for g := 0; g < gc; g++ {
+			taken = append(taken, <-results...)
+		}
+		wg.Wait()
+		expectElements(t, taken, opts, 0, gc*perG)
+		if !q.IsEmpty() {
+			t.Fatal("queue should be empty")
+		}
+	})
+}
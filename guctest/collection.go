@@ -0,0 +1,221 @@
+package guctest
+
+import (
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+// TestCollection runs the Collection suite, newColl must return an empty
+// collection which supports Iterator().Remove()
+func TestCollection(t *testing.T, newColl func() guc.Collection, opts *Options) {
+	opts = defaultOptions(opts)
+	n := opts.size()
+
+	opts.run(t, "Empty", func(t *testing.T) {
+		c := newColl()
+		if !c.IsEmpty() || c.Size() != 0 || len(c.ToArray()) != 0 {
+			t.Fatal("new collection should be empty")
+		}
+		if c.Iterator().HasNext() {
+			t.Fatal("iterator of an empty collection should have no element")
+		}
+		for range c.All() {
+			t.Fatal("all of an empty collection should yield nothing")
+		}
+		if c.Contains(opts.element(0)) || c.Remove(opts.element(0)) {
+			t.Fatal("empty collection should contain nothing")
+		}
+	})
+
+	opts.run(t, "AddContains", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		if c.IsEmpty() || c.Size() != n {
+			t.Fatalf("size is %d, expected %d", c.Size(), n)
+		}
+		for i := 0; i < n; i++ {
+			if !c.Contains(opts.element(i)) {
+				t.Fatalf("should contain element %d", i)
+			}
+		}
+		if c.Contains(opts.element(n)) {
+			t.Fatal("should not contain an element never added")
+		}
+	})
+
+	opts.run(t, "Iterator", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		it := c.Iterator()
+		if !it.HasNext() || !it.HasNext() {
+			t.Fatal("iterator should have next")
+		}
+		elements := make([]interface{}, 0, n)
+		for it.HasNext() {
+			elements = append(elements, it.Next())
+		}
+		expectElements(t, elements, opts, 0, n)
+		it = c.Iterator()
+		it.Next()
+		elements = elements[:0]
+		it.ForEachRemaining(func(i interface{}) {
+			elements = append(elements, i)
+		})
+		if len(elements) != n-1 {
+			t.Fatal("for each remaining should visit the remaining elements")
+		}
+	})
+
+	opts.run(t, "IteratorRemove", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		removed := make(map[interface{}]bool)
+		it := c.Iterator()
+		for idx := 0; it.HasNext(); idx++ {
+			e := it.Next()
+			if idx%2 == 0 {
+				it.Remove()
+				removed[e] = true
+			}
+		}
+		if c.Size() != n-len(removed) {
+			t.Fatalf("size is %d after iterator remove, expected %d", c.Size(), n-len(removed))
+		}
+		for i := 0; i < n; i++ {
+			e := opts.element(i)
+			if c.Contains(e) == removed[e] {
+				t.Fatalf("element %d should be removed: %v", i, removed[e])
+			}
+		}
+	})
+
+	opts.run(t, "All", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		elements := make([]interface{}, 0, n)
+		for e := range c.All() {
+			elements = append(elements, e)
+		}
+		expectElements(t, elements, opts, 0, n)
+		cnt := 0
+		for range c.All() {
+			cnt++
+			if cnt == 2 {
+				break
+			}
+		}
+		elements = elements[:0]
+		c.ForEach(func(i interface{}) {
+			elements = append(elements, i)
+		})
+		expectElements(t, elements, opts, 0, n)
+	})
+
+	opts.run(t, "ToArray", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		expectElements(t, c.ToArray(), opts, 0, n)
+		expectElements(t, c.FillArray(make([]interface{}, 1)), opts, 0, n)
+		arr := make([]interface{}, n+3)
+		r := c.FillArray(arr)
+		expectElements(t, r, opts, 0, n)
+		if &r[0] != &arr[0] {
+			t.Fatal("fill array should use arr if it is large enough")
+		}
+	})
+
+	opts.run(t, "Remove", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		if !c.Remove(opts.element(0)) || c.Contains(opts.element(0)) || c.Size() != n-1 {
+			t.Fatal("remove of an existing element error")
+		}
+		if c.Remove(opts.element(0)) || c.Remove(opts.element(n)) || c.Size() != n-1 {
+			t.Fatal("remove of a missing element should return false")
+		}
+	})
+
+	opts.run(t, "Bulk", func(t *testing.T) {
+		c := newColl()
+		if !c.AddAll(newSliceCollection(opts.element(0), opts.element(1))) || c.Size() != 2 {
+			t.Fatal("add all error")
+		}
+		if c.AddAll(newSliceCollection()) {
+			t.Fatal("add all of an empty collection should return false")
+		}
+		fill(t, c, opts, 2, n)
+		if !c.ContainsAll(newSliceCollection(opts.element(0), opts.element(n-1))) {
+			t.Fatal("contains all should be true")
+		}
+		if c.ContainsAll(newSliceCollection(opts.element(0), opts.element(n))) {
+			t.Fatal("contains all should be false")
+		}
+		if !c.RemoveAll(newSliceCollection(opts.element(0), opts.element(n))) || c.Size() != n-1 {
+			t.Fatal("remove all error")
+		}
+		if c.RemoveAll(newSliceCollection(opts.element(n))) {
+			t.Fatal("remove all of missing elements should return false")
+		}
+		retain := newSliceCollection()
+		for i := 1; i < n; i += 2 {
+			retain.Add(opts.element(i))
+		}
+		if !c.RetainAll(retain) || c.Size() != retain.Size() {
+			t.Fatal("retain all error")
+		}
+		if c.RetainAll(retain) {
+			t.Fatal("retain all should return false if nothing changed")
+		}
+		for _, e := range retain.data {
+			if !c.Contains(e) {
+				t.Fatal("retain all should keep the retained elements")
+			}
+		}
+	})
+
+	opts.run(t, "RemoveIf", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		odd := make(map[interface{}]bool)
+		for i := 1; i < n; i += 2 {
+			odd[opts.element(i)] = true
+		}
+		for removed := 1; removed <= len(odd); removed++ {
+			if !c.RemoveIf(func(i interface{}) bool { return odd[i] }) {
+				t.Fatal("remove if should return true")
+			}
+			if c.Size() != n-removed {
+				t.Fatalf("size is %d after remove if, expected %d", c.Size(), n-removed)
+			}
+		}
+		if c.RemoveIf(func(i interface{}) bool { return odd[i] }) {
+			t.Fatal("remove if should return false if nothing matches")
+		}
+		for i := 0; i < n; i += 2 {
+			if !c.Contains(opts.element(i)) {
+				t.Fatal("remove if should only remove matching elements")
+			}
+		}
+	})
+
+	opts.run(t, "Clear", func(t *testing.T) {
+		c := newColl()
+		fill(t, c, opts, 0, n)
+		c.Clear()
+		if !c.IsEmpty() || c.Size() != 0 || c.Contains(opts.element(0)) {
+			t.Fatal("collection should be empty after clear")
+		}
+		fill(t, c, opts, 0, 1)
+		if c.Size() != 1 {
+			t.Fatal("collection should be usable after clear")
+		}
+	})
+
+	opts.run(t, "Equals", func(t *testing.T) {
+		c := newColl()
+		if !c.Equals(c) || c.HashCode() != c.HashCode() {
+			t.Fatal("collection should equal itself")
+		}
+	})
+}
@@ -0,0 +1,165 @@
+// Package guctest is a conformance kit for implementations of the guc
+// collection interfaces. Each Test function runs a behavioral suite as
+// subtests against a factory returning new empty instances:
+//
+//	func TestMyQueue(t *testing.T) {
+//		guctest.TestQueue(t, func() guc.Queue { return NewMyQueue() }, nil)
+//	}
+package guctest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+// Options tunes the suites, nil means all defaults
+type Options struct {
+	// Element returns the i-th element, elements of different i must not be
+	// equal and must be usable as map keys. default is i itself
+	Element func(i int) interface{}
+	// if not nil, polling must return elements in this order
+	Comparator guc.Comparator
+	// Poll may remove an element other than the one returned by Peek
+	Relaxed bool
+	// capacity of a bounded queue, 0 means unbounded
+	Capacity int
+	// enables the concurrent stress tests of TestQueue,
+	// always enabled by TestBlockingQueue
+	Concurrent bool
+	// names of subtests to skip, e.g. "PollWithTimeout"
+	Skip []string
+}
+
+func (this *Options) element(i int) interface{} {
+	if this.Element == nil {
+		return i
+	}
+	return this.Element(i)
+}
+
+// size returns how many elements the suites put in a collection
+func (this *Options) size() int {
+	if this.Capacity > 0 && this.Capacity < 16 {
+		return this.Capacity
+	}
+	return 16
+}
+
+func (this *Options) run(t *testing.T, name string, f func(t *testing.T)) {
+	t.Run(name, func(t *testing.T) {
+		if slices.Contains(this.Skip, name) {
+			t.Skip("skipped by options")
+		}
+		f(t)
+	})
+}
+
+func defaultOptions(opts *Options) *Options {
+	if opts == nil {
+		return &Options{}
+	}
+	return opts
+}
+
+// fill adds the elements [from, to) to coll
+func fill(t *testing.T, coll guc.Collection, opts *Options, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		if !coll.Add(opts.element(i)) {
+			t.Fatalf("add of element %d returned false", i)
+		}
+	}
+}
+
+// expectElements checks that elements holds exactly the elements [from, to)
+func expectElements(t *testing.T, elements []interface{}, opts *Options, from, to int) {
+	t.Helper()
+	expected := make(map[interface{}]int)
+	for i := from; i < to; i++ {
+		expected[opts.element(i)]++
+	}
+	for _, e := range elements {
+		if expected[e] == 0 {
+			t.Fatalf("unexpected element %v", e)
+		}
+		expected[e]--
+	}
+	if len(elements) != to-from {
+		t.Fatalf("got %d elements, expected %d", len(elements), to-from)
+	}
+}
+
+func expectPanic(t *testing.T, name string, f func()) {
+	t.Helper()
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		f()
+		return
+	}()
+	if !r {
+		t.Fatal(name, "should panic")
+	}
+}
+
+// sliceCollection is a plain collection for the bulk operations
+type sliceCollection struct {
+	guc.AbstractCollection
+	data []interface{}
+}
+
+func newSliceCollection(data ...interface{}) *sliceCollection {
+	c := &sliceCollection{data: data}
+	c.InitAbstractCollection(c)
+	return c
+}
+
+type sliceIter struct {
+	coll    *sliceCollection
+	idx     int
+	lastRet int
+}
+
+func (this *sliceIter) HasNext() bool {
+	return this.idx < len(this.coll.data)
+}
+
+func (this *sliceIter) Next() interface{} {
+	if !this.HasNext() {
+		panic("no such element")
+	}
+	this.lastRet = this.idx
+	this.idx++
+	return this.coll.data[this.lastRet]
+}
+
+func (this *sliceIter) Remove() {
+	if this.lastRet < 0 {
+		panic("illegal state")
+	}
+	this.coll.data = slices.Delete(this.coll.data, this.lastRet, this.lastRet+1)
+	this.idx = this.lastRet
+	this.lastRet = -1
+}
+
+func (this *sliceIter) ForEachRemaining(consumer func(i interface{})) {
+	for this.HasNext() {
+		consumer(this.Next())
+	}
+}
+
+func (this *sliceCollection) Iterator() guc.Iterator {
+	return &sliceIter{coll: this, lastRet: -1}
+}
+
+func (this *sliceCollection) Size() int {
+	return len(this.data)
+}
+
+func (this *sliceCollection) Add(i interface{}) bool {
+	this.data = append(this.data, i)
+	return true
+}
@@ -0,0 +1,71 @@
+package guctest
+
+import (
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+func TestSliceCollection(t *testing.T) {
+	TestCollection(t, func() guc.Collection { return newSliceCollection() }, nil)
+}
+
+func TestPriorityQueue(t *testing.T) {
+	TestQueue(t, func() guc.Queue {
+		return guc.NewPriorityWithComparator(guc.NaturalOrder())
+	}, &Options{Comparator: guc.NaturalOrder()})
+}
+
+func TestStablePriorityQueue(t *testing.T) {
+	TestQueue(t, func() guc.Queue {
+		return guc.NewStablePriority(guc.NaturalOrder())
+	}, &Options{Comparator: guc.NaturalOrder()})
+}
+
+func TestPriorityBlockingQueue(t *testing.T) {
+	TestBlockingQueue(t, func() guc.BlockingQueue {
+		return guc.NewPriorityBlockingQueueWithComparator(guc.NaturalOrder())
+	}, &Options{
+		Comparator: guc.NaturalOrder(),
+	})
+}
+
+func TestBoundedPriorityBlockingQueue(t *testing.T) {
+	TestBlockingQueue(t, func() guc.BlockingQueue {
+		return guc.NewBoundedPriorityBlockingQueue(8, guc.NaturalOrder())
+	}, &Options{
+		Comparator: guc.NaturalOrder(),
+		Capacity:   8,
+	})
+}
+
+func TestConcurrentPriorityQueue(t *testing.T) {
+	TestQueue(t, func() guc.Queue {
+		return guc.NewConcurrentPriorityQueueWithShards(4, guc.NaturalOrder())
+	}, &Options{Relaxed: true, Concurrent: true})
+}
+
+func TestCopyOnWriteArrayList(t *testing.T) {
+	TestList(t, func() guc.List {
+		return guc.NewCopyOnWriteArrayList()
+	}, &Options{
+		Concurrent: true,
+		// iterators are read only snapshots
+		Skip: []string{"IteratorRemove"},
+	})
+}
+
+func TestCopyOnWriteArraySet(t *testing.T) {
+	TestSet(t, func() guc.Set {
+		return guc.NewCopyOnWriteArraySet()
+	}, &Options{
+		Concurrent: true,
+		Skip:       []string{"IteratorRemove"},
+	})
+}
+
+func TestConcurrentHashSet(t *testing.T) {
+	TestSet(t, func() guc.Set {
+		return guc.NewConcurrentHashSet()
+	}, &Options{Concurrent: true})
+}
@@ -0,0 +1,142 @@
+package guctest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+// TestList runs the Collection suite and the List suite,
+// newList must return an empty list
+func TestList(t *testing.T, newList func() guc.List, opts *Options) {
+	opts = defaultOptions(opts)
+	n := opts.size()
+
+	TestCollection(t, func() guc.Collection { return newList() }, opts)
+
+	opts.run(t, "Order", func(t *testing.T) {
+		l := newList()
+		fill(t, l, opts, 0, n)
+		for i := 0; i < n; i++ {
+			if l.Get(i) != opts.element(i) || l.IndexOf(opts.element(i)) != i {
+				t.Fatalf("element %d is out of order", i)
+			}
+		}
+		idx := 0
+		for e := range l.All() {
+			if e != opts.element(idx) {
+				t.Fatalf("all yields element %d out of order", idx)
+			}
+			idx++
+		}
+		if l.IndexOf(opts.element(n)) != -1 || l.LastIndexOf(opts.element(n)) != -1 {
+			t.Fatal("index of a missing element should be -1")
+		}
+		l.Add(opts.element(0))
+		if l.IndexOf(opts.element(0)) != 0 || l.LastIndexOf(opts.element(0)) != n {
+			t.Fatal("index of and last index of error")
+		}
+	})
+
+	opts.run(t, "Index", func(t *testing.T) {
+		l := newList()
+		fill(t, l, opts, 1, 3)
+		l.AddAt(0, opts.element(0))
+		l.AddAt(3, opts.element(4))
+		l.AddAt(3, opts.element(3))
+		for i := 0; i < 5; i++ {
+			if l.Get(i) != opts.element(i) {
+				t.Fatalf("element %d is out of order after add at", i)
+			}
+		}
+		if l.Set(2, opts.element(5)) != opts.element(2) || l.Get(2) != opts.element(5) || l.Size() != 5 {
+			t.Fatal("set should replace the element")
+		}
+		if l.RemoveAt(2) != opts.element(5) || l.Size() != 4 || l.Get(2) != opts.element(3) {
+			t.Fatal("remove at should shift the following elements")
+		}
+		expectPanic(t, "Get out of range", func() { l.Get(4) })
+		expectPanic(t, "Get of negative index", func() { l.Get(-1) })
+		expectPanic(t, "Set out of range", func() { l.Set(4, opts.element(0)) })
+		expectPanic(t, "AddAt out of range", func() { l.AddAt(5, opts.element(0)) })
+		expectPanic(t, "RemoveAt out of range", func() { l.RemoveAt(4) })
+	})
+
+	opts.run(t, "SubList", func(t *testing.T) {
+		l := newList()
+		fill(t, l, opts, 0, n)
+		s := l.SubList(1, 3)
+		if s.Size() != 2 || s.Get(0) != opts.element(1) || s.Get(1) != opts.element(2) {
+			t.Fatal("sub list error")
+		}
+		if l.SubList(2, 2).Size() != 0 {
+			t.Fatal("sub list should be empty")
+		}
+		expectPanic(t, "SubList out of range", func() { l.SubList(0, n+1) })
+		expectPanic(t, "SubList with from > to", func() { l.SubList(2, 1) })
+	})
+
+	opts.run(t, "ListIterator", func(t *testing.T) {
+		l := newList()
+		fill(t, l, opts, 0, n)
+		it := l.ListIterator()
+		if it.HasPrevious() || it.NextIndex() != 0 || it.PreviousIndex() != -1 {
+			t.Fatal("list iterator should start at 0")
+		}
+		for i := 0; i < n; i++ {
+			if it.Next() != opts.element(i) {
+				t.Fatalf("next should return element %d", i)
+			}
+		}
+		if it.HasNext() || it.NextIndex() != n {
+			t.Fatal("list iterator should be at the end")
+		}
+		for i := n - 1; i >= 0; i-- {
+			if it.Previous() != opts.element(i) {
+				t.Fatalf("previous should return element %d", i)
+			}
+		}
+		if it.HasPrevious() {
+			t.Fatal("list iterator should be at the start")
+		}
+	})
+
+	opts.run(t, "ListEquals", func(t *testing.T) {
+		l1, l2 := newList(), newList()
+		fill(t, l1, opts, 0, n)
+		fill(t, l2, opts, 0, n)
+		if !l1.Equals(l2) || l1.HashCode() != l2.HashCode() {
+			t.Fatal("lists with the same elements should be equal")
+		}
+		l2.RemoveAt(0)
+		l2.Add(opts.element(0))
+		if l1.Equals(l2) || l2.Equals(l1) {
+			t.Fatal("lists in another order should not be equal")
+		}
+	})
+
+	if !opts.Concurrent {
+		return
+	}
+
+	opts.run(t, "ConcurrentAdd", func(t *testing.T) {
+		l := newList()
+		gc, perG := 4, 200
+		var wg sync.WaitGroup
+		for g := 0; g < gc; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := g * perG; i < (g+1)*perG; i++ {
+					l.Add(opts.element(i))
+					// readers must never see a torn list
+					for range l.All() {
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		expectElements(t, l.ToArray(), opts, 0, gc*perG)
+	})
+}
@@ -0,0 +1,122 @@
+package guctest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+// TestQueue runs the Collection suite and the Queue suite,
+// newQueue must return an empty queue
+func TestQueue(t *testing.T, newQueue func() guc.Queue, opts *Options) {
+	opts = defaultOptions(opts)
+	n := opts.size()
+
+	TestCollection(t, func() guc.Collection { return newQueue() }, opts)
+
+	opts.run(t, "EmptyHead", func(t *testing.T) {
+		q := newQueue()
+		if q.Poll() != nil || q.Peek() != nil {
+			t.Fatal("poll and peek of an empty queue should be nil")
+		}
+		expectPanic(t, "RemoveHead of an empty queue", func() { q.RemoveHead() })
+		expectPanic(t, "Element of an empty queue", func() { q.Element() })
+	})
+
+	opts.run(t, "Head", func(t *testing.T) {
+		q := newQueue()
+		for i := 0; i < n; i++ {
+			if !q.Offer(opts.element(i)) {
+				t.Fatalf("offer of element %d returned false", i)
+			}
+		}
+		polled := make([]interface{}, 0, n)
+		for !q.IsEmpty() {
+			head := q.Peek()
+			if head == nil || q.Element() != head || q.Size() != n-len(polled) {
+				t.Fatal("peek and element should return the head without removing it")
+			}
+			var i interface{}
+			if len(polled)%2 == 0 {
+				i = q.Poll()
+			} else {
+				i = q.RemoveHead()
+			}
+			if !opts.Relaxed && i != head {
+				t.Fatalf("removed %v, but the head was %v", i, head)
+			}
+			if opts.Comparator != nil && len(polled) > 0 &&
+				opts.Comparator.Compare(polled[len(polled)-1], i) > 0 {
+				t.Fatalf("%v polled after %v", i, polled[len(polled)-1])
+			}
+			polled = append(polled, i)
+		}
+		expectElements(t, polled, opts, 0, n)
+		if q.Poll() != nil {
+			t.Fatal("poll of a drained queue should be nil")
+		}
+	})
+
+	opts.run(t, "QueueEquals", func(t *testing.T) {
+		q1, q2 := newQueue(), newQueue()
+		fill(t, q1, opts, 0, n)
+		for i := n - 1; i >= 0; i-- {
+			q2.Offer(opts.element(i))
+		}
+		if !q1.Equals(q2) || !q2.Equals(q1) || q1.HashCode() != q2.HashCode() {
+			t.Fatal("queues with the same elements should be equal")
+		}
+		q2.Remove(opts.element(0))
+		if q1.Equals(q2) || q2.Equals(q1) {
+			t.Fatal("queues with other elements should not be equal")
+		}
+	})
+
+	if !opts.Concurrent {
+		return
+	}
+
+	opts.run(t, "ConcurrentOfferPoll", func(t *testing.T) {
+		q := newQueue()
+		gc, perG := 4, 2000
+		if opts.Capacity > 0 {
+			perG = opts.Capacity / gc
+		}
+		var wg sync.WaitGroup
+		for g := 0; g < gc; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := g * perG; i < (g+1)*perG; i++ {
+					if !q.Offer(opts.element(i)) {
+						t.Errorf("offer of element %d returned false", i)
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		if q.Size() != gc*perG {
+			t.Fatalf("size is %d, expected %d", q.Size(), gc*perG)
+		}
+		results := make(chan []interface{}, gc)
+		for g := 0; g < gc; g++ {
+			go func() {
+				polled := make([]interface{}, 0)
+				for i := q.Poll(); i != nil; i = q.Poll() {
+					polled = append(polled, i)
+				}
+				results <- polled
+			}()
+		}
+		polled := make([]interface{}, 0, gc*perG)
+		for g := 0; g < gc; g++ {
+			polled = append(polled, <-results...)
+		}
+		expectElements(t, polled, opts, 0, gc*perG)
+		if !q.IsEmpty() {
+			t.Fatal("queue should be empty")
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package guctest
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/better-concurrent/guc"
+)
+
+// TestSet runs the Collection suite and the Set suite,
+// newSet must return an empty set
+func TestSet(t *testing.T, newSet func() guc.Set, opts *Options) {
+	opts = defaultOptions(opts)
+	n := opts.size()
+
+	TestCollection(t, func() guc.Collection { return newSet() }, opts)
+
+	opts.run(t, "Unique", func(t *testing.T) {
+		s := newSet()
+		fill(t, s, opts, 0, n)
+		for i := 0; i < n; i++ {
+			if s.Add(opts.element(i)) {
+				t.Fatalf("add of the present element %d should return false", i)
+			}
+		}
+		other := newSliceCollection(opts.element(0), opts.element(n), opts.element(n))
+		if !s.AddAll(other) || s.Size() != n+1 {
+			t.Fatal("add all should only add absent elements once")
+		}
+		if s.AddAll(other) {
+			t.Fatal("add all of present elements should return false")
+		}
+	})
+
+	opts.run(t, "Algebra", func(t *testing.T) {
+		s1, s2 := newSet(), newSet()
+		fill(t, s1, opts, 0, 6)
+		fill(t, s2, opts, 3, 9)
+		expectElements(t, s1.Union(s2).ToArray(), opts, 0, 9)
+		expectElements(t, s1.Intersect(s2).ToArray(), opts, 3, 6)
+		expectElements(t, s1.Difference(s2).ToArray(), opts, 0, 3)
+		expectElements(t, s2.Difference(s1).ToArray(), opts, 6, 9)
+		if s1.Intersect(newSet()).Size() != 0 || s1.Difference(newSet()).Size() != 6 {
+			t.Fatal("algebra with an empty set error")
+		}
+		expectElements(t, s1.ToArray(), opts, 0, 6)
+	})
+
+	opts.run(t, "SetEquals", func(t *testing.T) {
+		s1, s2 := newSet(), newSet()
+		fill(t, s1, opts, 0, n)
+		for i := n - 1; i >= 0; i-- {
+			s2.Add(opts.element(i))
+		}
+		if !s1.Equals(s2) || !s2.Equals(s1) || s1.HashCode() != s2.HashCode() {
+			t.Fatal("sets with the same elements should be equal")
+		}
+		s2.Remove(opts.element(0))
+		s2.Add(opts.element(n))
+		if s1.Equals(s2) || s2.Equals(s1) {
+			t.Fatal("sets with other elements should not be equal")
+		}
+	})
+
+	if !opts.Concurrent {
+		return
+	}
+
+	opts.run(t, "ConcurrentAdd", func(t *testing.T) {
+		s := newSet()
+		gc, total := 4, 500
+		added := make(chan int, gc)
+		var wg sync.WaitGroup
+		for g := 0; g < gc; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cnt := 0
+				for i := 0; i < total; i++ {
+					if s.Add(opts.element(i)) {
+						cnt++
+					}
+				}
+				added <- cnt
+			}()
+		}
+		wg.Wait()
+		sum := 0
+		for g := 0; g < gc; g++ {
+			sum += <-added
+		}
+		if sum != total {
+			t.Fatalf("%d adds returned true, expected %d", sum, total)
+		}
+		expectElements(t, s.ToArray(), opts, 0, total)
+	})
+}
@@ -0,0 +1,99 @@
+package guc
+
+import (
+	"iter"
+)
+
+// IteratorSeq adapts an Iterator to a range-over-func sequence
+func IteratorSeq(it Iterator) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for it.HasNext() {
+			if !yield(it.Next()) {
+				return
+			}
+		}
+	}
+}
+
+// SeqIter is the Iterator returned by SeqIterator
+type SeqIter struct {
+	next func() (any, bool)
+	stop func()
+	// lookahead filled by HasNext
+	value    any
+	hasValue bool
+	done     bool
+}
+
+// SeqIterator adapts a sequence to an Iterator, Remove is not supported.
+// the sequence is released when it is exhausted, call Stop on the returned
+// iterator to release it earlier
+func SeqIterator(seq iter.Seq[any]) *SeqIter {
+	next, stop := iter.Pull(seq)
+	return &SeqIter{next: next, stop: stop}
+}
+
+var _ Iterator = new(SeqIter)
+
+func (this *SeqIter) HasNext() bool {
+	if this.hasValue {
+		return true
+	}
+	if this.done {
+		return false
+	}
+	v, ok := this.next()
+	if !ok {
+		this.Stop()
+		return false
+	}
+	this.value, this.hasValue = v, true
+	return true
+}
+
+func (this *SeqIter) Next() interface{} {
+	if !this.HasNext() {
+		panic("no more elements")
+	}
+	v := this.value
+	this.value, this.hasValue = nil, false
+	return v
+}
+
+func (this *SeqIter) Remove() {
+	panic("remove is not supported")
+}
+
+func (this *SeqIter) ForEachRemaining(consumer func(i interface{})) {
+	for this.HasNext() {
+		consumer(this.Next())
+	}
+}
+
+// Stop releases the underlying sequence
+func (this *SeqIter) Stop() {
+	if !this.done {
+		this.done = true
+		this.stop()
+	}
+}
+
+func sliceSeq(arr []interface{}) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for _, v := range arr {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+func sliceBackwardSeq(arr []interface{}) iter.Seq[any] {
+	return func(yield func(any) bool) {
+		for i := len(arr) - 1; i >= 0; i-- {
+			if !yield(arr[i]) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package guc
+
+import (
+	"testing"
+)
+
+func TestIterator_HasNextIdempotent(t *testing.T) {
+	iters := []Iterator{
+		newPreparedPriorityQueue().Iterator(),
+		newPreparedPriorityBlockingQueue().Iterator(),
+	}
+	for _, iter := range iters {
+		cnt := 0
+		for iter.HasNext() && iter.HasNext() {
+			iter.Next()
+			cnt++
+		}
+		if cnt != 7 {
+			t.Fatal("calling HasNext twice should not skip elements")
+		}
+	}
+}
+
+func TestPriorityQueue_All(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	cnt := 0
+	for v := range p.All() {
+		if v == nil {
+			t.Fatal("element should not be nil")
+		}
+		cnt++
+	}
+	if cnt != 7 {
+		t.Fatal("all count should be 7")
+	}
+	for range p.All() {
+		break
+	}
+
+	prev := -1
+	for v := range p.Sorted() {
+		if v.(*sampleItem).Value < prev {
+			t.Fatal("sorted should follow priority order")
+		}
+		prev = v.(*sampleItem).Value
+	}
+	prev = 100
+	for v := range p.Backward() {
+		if v.(*sampleItem).Value > prev {
+			t.Fatal("backward should follow reverse priority order")
+		}
+		prev = v.(*sampleItem).Value
+	}
+}
+
+func TestPriorityBlockingQueue_All(t *testing.T) {
+	p := newPreparedPriorityBlockingQueue()
+	cnt := 0
+	for v := range p.All() {
+		// modifying the queue while ranging over the snapshot is safe
+		p.Remove(v)
+		cnt++
+	}
+	if cnt != 7 || !p.IsEmpty() {
+		t.Fatal("all elements should be removed")
+	}
+	p = newPreparedPriorityBlockingQueue()
+	var first interface{}
+	for v := range p.Backward() {
+		first = v
+		break
+	}
+	if first.(*sampleBlockingItem).Value != 33 {
+		t.Fatal("backward should start from the lowest priority")
+	}
+}
+
+func TestSeqIterator(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	iter := SeqIterator(p.Sorted())
+	prev := -1
+	cnt := 0
+	for iter.HasNext() && iter.HasNext() {
+		v := iter.Next().(*sampleItem).Value
+		if v < prev {
+			t.Fatal("iterator should follow the sequence")
+		}
+		prev = v
+		cnt++
+	}
+	if cnt != 7 {
+		t.Fatal("iter count should be 7")
+	}
+
+	iter = SeqIterator(p.All())
+	iter.Next()
+	iter.Stop()
+	if iter.HasNext() {
+		t.Fatal("stopped iterator should have no element")
+	}
+
+	cnt = 0
+	for range IteratorSeq(p.Iterator()) {
+		cnt++
+	}
+	if cnt != 7 {
+		t.Fatal("seq count should be 7")
+	}
+}
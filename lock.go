@@ -0,0 +1,65 @@
+package guc
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ownerIds int64
+
+// Owner is the token a reentrant lock is held by, since goroutines have no
+// identity. Use one Owner per logical thread of control, it may move
+// between goroutines but must not be used by two of them at once
+type Owner struct {
+	id int64
+}
+
+func NewOwner() *Owner {
+	return &Owner{id: atomic.AddInt64(&ownerIds, 1)}
+}
+
+func (this *Owner) String() string {
+	return "Owner-" + strconv.FormatInt(this.id, 10)
+}
+
+func checkOwner(owner *Owner) {
+	if owner == nil {
+		panic("owner is nil!")
+	}
+}
+
+// Lock is a lock held by an Owner
+type Lock interface {
+	// Lock blocks until owner holds the lock
+	Lock(owner *Owner)
+	// LockContext is like Lock, it returns ctx.Err() if ctx is done first
+	LockContext(ctx context.Context, owner *Owner) error
+	// TryLock acquires the lock only if it is free at the time of invocation
+	TryLock(owner *Owner) bool
+	// TryLockWithTimeout waits at most timeout for the lock
+	TryLockWithTimeout(owner *Owner, timeout time.Duration) bool
+	// Unlock panics with ErrNotOwner if owner doesn't hold the lock
+	Unlock(owner *Owner)
+}
+
+// Locker returns a sync.Locker which locks l as owner
+func Locker(l Lock, owner *Owner) sync.Locker {
+	checkOwner(owner)
+	return &ownedLocker{l: l, owner: owner}
+}
+
+type ownedLocker struct {
+	l     Lock
+	owner *Owner
+}
+
+func (this *ownedLocker) Lock() {
+	this.l.Lock(this.owner)
+}
+
+func (this *ownedLocker) Unlock() {
+	this.l.Unlock(this.owner)
+}
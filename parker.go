@@ -0,0 +1,120 @@
+package guc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// status of a Parker, in the low bits of its state, the high bits count
+// the parks so a stale timer can't wake a later one
+const (
+	parkEmpty uint64 = iota
+	parkPermit
+	parkParked
+
+	parkStatusMask = 3
+	parkGen        = 4
+)
+
+// Parker is a parking permit for one goroutine, the building block of
+// blocking synchronizers. The permit is binary: Unpark makes it available,
+// Park consumes it or waits for it, so an Unpark before a Park is not lost
+// and many Unparks give one permit.
+//
+// Only the owning goroutine may park, a concurrent Park panics with
+// ErrIllegalState, any goroutine may unpark. A timed park also returns when
+// its deadline passes, callers should check their condition in a loop.
+// The zero value has no permit
+type Parker struct {
+	// Volatile
+	state uint64
+	sema  uint32
+}
+
+func NewParker() *Parker {
+	return &Parker{}
+}
+
+// Park waits until the permit is available and consumes it
+func (this *Parker) Park() {
+	this.park(time.Time{})
+}
+
+// ParkNanos is like Park, it waits at most timeout
+func (this *Parker) ParkNanos(timeout time.Duration) {
+	if timeout <= 0 {
+		this.tryConsume()
+		return
+	}
+	this.park(time.Now().Add(timeout))
+}
+
+// ParkUntil is like Park, it waits at most until deadline
+func (this *Parker) ParkUntil(deadline time.Time) {
+	if !time.Now().Before(deadline) {
+		this.tryConsume()
+		return
+	}
+	this.park(deadline)
+}
+
+// Unpark makes the permit available, waking the parked goroutine if any
+func (this *Parker) Unpark() {
+	for {
+		s := atomic.LoadUint64(&this.state)
+		switch s & parkStatusMask {
+		case parkPermit:
+			return
+		case parkEmpty:
+			if atomic.CompareAndSwapUint64(&this.state, s, s|parkPermit) {
+				return
+			}
+		case parkParked:
+			if this.wake(s) {
+				return
+			}
+		}
+	}
+}
+
+// wake ends the park of state s, return false if it already ended
+func (this *Parker) wake(s uint64) bool {
+	if atomic.CompareAndSwapUint64(&this.state, s, s&^parkStatusMask|parkEmpty) {
+		SyncRuntimeSemrelease(&this.sema, false)
+		return true
+	}
+	return false
+}
+
+func (this *Parker) tryConsume() bool {
+	s := atomic.LoadUint64(&this.state)
+	return s&parkStatusMask == parkPermit &&
+		atomic.CompareAndSwapUint64(&this.state, s, s&^parkStatusMask|parkEmpty)
+}
+
+func (this *Parker) park(deadline time.Time) {
+	for {
+		s := atomic.LoadUint64(&this.state)
+		switch s & parkStatusMask {
+		case parkPermit:
+			if this.tryConsume() {
+				return
+			}
+		case parkParked:
+			panic(ErrIllegalState)
+		case parkEmpty:
+			parked := (s&^parkStatusMask + parkGen) | parkParked
+			if !atomic.CompareAndSwapUint64(&this.state, s, parked) {
+				continue
+			}
+			if !deadline.IsZero() {
+				t := time.AfterFunc(time.Until(deadline), func() {
+					this.wake(parked)
+				})
+				defer t.Stop()
+			}
+			SyncRuntimeSemacquire(&this.sema)
+			return
+		}
+	}
+}
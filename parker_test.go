@@ -0,0 +1,67 @@
+package guc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParker(t *testing.T) {
+	var p Parker
+	p.Unpark()
+	p.Unpark()
+	done := make(chan bool)
+	go func() {
+		p.Park()
+		done <- true
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("unpark before park should not be lost")
+	}
+	start := time.Now()
+	p.ParkNanos(20 * time.Millisecond)
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("permits should not accumulate")
+	}
+	p.ParkUntil(time.Now().Add(-time.Second))
+	p.ParkNanos(0)
+
+	// the timer of an earlier park must not wake a later one
+	p.ParkNanos(time.Millisecond)
+	var unparked int32
+	go func() {
+		p.Park()
+		if atomic.LoadInt32(&unparked) == 0 {
+			t.Error("park returned before unpark")
+		}
+		done <- true
+	}()
+	time.Sleep(20 * time.Millisecond)
+	atomic.StoreInt32(&unparked, 1)
+	p.Unpark()
+	<-done
+}
+
+func TestParker_Concurrent(t *testing.T) {
+	// a ping pong between two goroutines through their parkers
+	p1, p2 := NewParker(), NewParker()
+	var turn int32
+	var wg sync.WaitGroup
+	play := func(me, other *Parker, mine int32) {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			for atomic.LoadInt32(&turn) != mine {
+				me.ParkNanos(time.Millisecond)
+			}
+			atomic.StoreInt32(&turn, 1-mine)
+			other.Unpark()
+		}
+	}
+	wg.Add(2)
+	go play(p1, p2, 0)
+	go play(p2, p1, 1)
+	wg.Wait()
+}
@@ -0,0 +1,296 @@
+package guc
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const maxPhase = math.MaxInt32
+
+// Phaser is a reusable barrier whose number of parties may change between
+// phases. Parties register, then arrive at each phase, the phase advances
+// when all the registered parties have arrived. Phase numbers start at 0
+// and wrap after math.MaxInt32, once terminated they are negative.
+//
+// Phasers may be tiered to reduce contention: a child phaser with parties
+// is registered as one party of its parent and arrives at the parent when
+// all its parties have arrived. The whole tree shares the phase of the root.
+//
+// Before each advance the root calls its OnAdvance hook, see SetOnAdvance,
+// the phaser terminates if it returns true. By default it terminates when
+// no party is registered
+type Phaser struct {
+	mu           sync.Mutex
+	parent, root *Phaser
+	// guarded by mu
+	onAdvance  func(phase, registeredParties int) bool
+	phase      int
+	terminated bool
+	parties    int
+	unarrived  int
+	// waiters for the phase to advance, root only
+	queue waitQueue
+}
+
+// NewPhaser creates a root phaser with parties registered for phase 0
+func NewPhaser(parties int) *Phaser {
+	return NewTieredPhaser(nil, parties)
+}
+
+// NewTieredPhaser creates a child of parent, which may be nil for a root
+func NewTieredPhaser(parent *Phaser, parties int) *Phaser {
+	if parties < 0 {
+		panic(ErrIllegalArgument)
+	}
+	p := &Phaser{parent: parent}
+	if parent == nil {
+		p.root = p
+	} else {
+		p.root = parent.root
+	}
+	p.BulkRegister(parties)
+	return p
+}
+
+// SetOnAdvance sets the hook called with the phase which completes and
+// the number of registered parties. It runs with the root locked and must
+// not use the phaser. Only the hook of the root is called
+func (this *Phaser) SetOnAdvance(onAdvance func(phase, registeredParties int) bool) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.onAdvance = onAdvance
+}
+
+// phaseOf returns the phase of the root, negative if terminated,
+// must be called with the root mu held
+func (this *Phaser) phaseOf() int {
+	if this.terminated {
+		return int(int32(this.phase) | math.MinInt32)
+	}
+	return this.phase
+}
+
+// reconcile catches up with the phase of the root and returns it,
+// must be called with mu held
+func (this *Phaser) reconcile() int {
+	if this.root == this {
+		return this.phaseOf()
+	}
+	this.root.mu.Lock()
+	phase := this.root.phaseOf()
+	this.root.mu.Unlock()
+	if phase >= 0 && phase != this.phase {
+		this.phase = phase
+		this.unarrived = this.parties
+	}
+	return phase
+}
+
+// Register adds a party, return the arrival phase number, negative if
+// terminated
+func (this *Phaser) Register() int {
+	return this.BulkRegister(1)
+}
+
+// BulkRegister adds parties, return the arrival phase number, negative if
+// terminated. on a child whose parties have all arrived it waits for the
+// root to advance
+func (this *Phaser) BulkRegister(parties int) int {
+	if parties < 0 {
+		panic(ErrIllegalArgument)
+	}
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	phase := this.reconcile()
+	for phase >= 0 && this.root != this && this.parties > 0 && this.unarrived == 0 {
+		// this has arrived at its parent, new parties belong to the next phase
+		this.mu.Unlock()
+		this.awaitAdvance(nil, phase, time.Time{})
+		this.mu.Lock()
+		phase = this.reconcile()
+	}
+	if phase < 0 || parties == 0 {
+		return phase
+	}
+	if this.root != this && this.parties == 0 {
+		if phase = this.parent.Register(); phase < 0 {
+			return phase
+		}
+		this.phase = phase
+	}
+	this.parties += parties
+	this.unarrived += parties
+	return phase
+}
+
+// Arrive arrives without waiting for the others, return the arrival phase
+// number, negative if terminated. panics with ErrIllegalState if all the
+// registered parties have arrived
+func (this *Phaser) Arrive() int {
+	return this.doArrive(false)
+}
+
+// ArriveAndDeregister arrives and leaves the phaser, see Arrive
+func (this *Phaser) ArriveAndDeregister() int {
+	return this.doArrive(true)
+}
+
+func (this *Phaser) doArrive(deregister bool) int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	phase := this.reconcile()
+	if phase < 0 {
+		return phase
+	}
+	if this.unarrived <= 0 {
+		panic(ErrIllegalState)
+	}
+	this.unarrived--
+	if deregister {
+		this.parties--
+	}
+	if this.unarrived > 0 {
+		return phase
+	}
+	if this.root != this {
+		// unarrived stays 0 until reconcile sees the next phase
+		this.parent.doArrive(this.parties == 0)
+		return phase
+	}
+	if this.advanceHook(phase, this.parties) {
+		this.terminated = true
+	} else {
+		this.phase = (phase + 1) & maxPhase
+	}
+	this.unarrived = this.parties
+	signalAll(&this.queue)
+	return phase
+}
+
+func (this *Phaser) advanceHook(phase, registeredParties int) bool {
+	if this.onAdvance == nil {
+		return registeredParties == 0
+	}
+	return this.onAdvance(phase, registeredParties)
+}
+
+// ArriveAndAwaitAdvance arrives and waits for the others, return the next
+// phase number, negative if terminated
+func (this *Phaser) ArriveAndAwaitAdvance() int {
+	phase := this.doArrive(false)
+	if phase < 0 {
+		return phase
+	}
+	next, _ := this.awaitAdvance(nil, phase, time.Time{})
+	return next
+}
+
+// AwaitAdvance waits for the phaser to leave phase, return at once if it
+// is not the current phase. return the next phase number, negative if
+// terminated
+func (this *Phaser) AwaitAdvance(phase int) int {
+	next, _ := this.awaitAdvance(nil, phase, time.Time{})
+	return next
+}
+
+// AwaitAdvanceContext is like AwaitAdvance, it returns ctx.Err() if ctx is
+// done first
+func (this *Phaser) AwaitAdvanceContext(ctx context.Context, phase int) (int, error) {
+	next, ok := this.awaitAdvance(ctx, phase, time.Time{})
+	if !ok {
+		return next, ctx.Err()
+	}
+	return next, nil
+}
+
+// AwaitAdvanceWithTimeout is like AwaitAdvance, it returns ErrTimeout if
+// the phase doesn't advance within timeout
+func (this *Phaser) AwaitAdvanceWithTimeout(phase int, timeout time.Duration) (int, error) {
+	next, ok := this.awaitAdvance(nil, phase, time.Now().Add(timeout))
+	if !ok {
+		return next, ErrTimeout
+	}
+	return next, nil
+}
+
+// awaitAdvance return the current phase and true once it isn't phase
+func (this *Phaser) awaitAdvance(ctx context.Context, phase int, deadline time.Time) (int, bool) {
+	root := this.root
+	if phase < 0 {
+		return phase, true
+	}
+	root.mu.Lock()
+	if cur := root.phaseOf(); cur != phase {
+		root.mu.Unlock()
+		return cur, true
+	}
+	if ctx != nil && ctx.Err() != nil {
+		root.mu.Unlock()
+		return phase, false
+	}
+	w := &waiter{}
+	root.queue.pushBack(w)
+	root.mu.Unlock()
+	signalled := w.await(ctx, deadline)
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.queue.remove(w)
+	cur := root.phaseOf()
+	return cur, signalled || cur != phase
+}
+
+// ForceTermination terminates the phaser and its tree, the waiting
+// parties are released
+func (this *Phaser) ForceTermination() {
+	root := this.root
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	root.terminated = true
+	signalAll(&root.queue)
+}
+
+func (this *Phaser) IsTerminated() bool {
+	return this.GetPhase() < 0
+}
+
+// GetPhase returns the current phase number, negative if terminated
+func (this *Phaser) GetPhase() int {
+	root := this.root
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return root.phaseOf()
+}
+
+func (this *Phaser) GetRegisteredParties() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.parties
+}
+
+// GetUnarrivedParties returns the number of parties which haven't arrived
+// at the current phase
+func (this *Phaser) GetUnarrivedParties() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.reconcile()
+	return this.unarrived
+}
+
+// GetArrivedParties returns the number of parties which have arrived at
+// the current phase
+func (this *Phaser) GetArrivedParties() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	this.reconcile()
+	return this.parties - this.unarrived
+}
+
+func (this *Phaser) GetParent() *Phaser {
+	return this.parent
+}
+
+func (this *Phaser) GetRoot() *Phaser {
+	return this.root
+}
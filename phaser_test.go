@@ -0,0 +1,144 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPhaser(t *testing.T) {
+	p := NewPhaser(1)
+	if p.Register() != 0 || p.GetRegisteredParties() != 2 || p.GetUnarrivedParties() != 2 {
+		t.Fatal("phaser should have 2 parties at phase 0")
+	}
+	if p.Arrive() != 0 || p.GetArrivedParties() != 1 || p.GetPhase() != 0 {
+		t.Fatal("phase should not advance before all parties arrive")
+	}
+	if _, err := p.AwaitAdvanceWithTimeout(0, 10*time.Millisecond); err != ErrTimeout {
+		t.Fatal("await advance should time out")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.AwaitAdvanceContext(ctx, 0); err != context.Canceled {
+		t.Fatal("await advance should return context.Canceled")
+	}
+	if p.Arrive() != 0 || p.GetPhase() != 1 || p.AwaitAdvance(0) != 1 || p.GetUnarrivedParties() != 2 {
+		t.Fatal("phase should advance once all parties arrived")
+	}
+	if p.ArriveAndDeregister() != 1 || p.GetRegisteredParties() != 1 {
+		t.Fatal("deregister error")
+	}
+	if p.ArriveAndDeregister() != 1 || !p.IsTerminated() || p.GetPhase() >= 0 {
+		t.Fatal("phaser without parties should terminate")
+	}
+	if p.Register() >= 0 || p.Arrive() >= 0 || p.AwaitAdvance(1) >= 0 {
+		t.Fatal("terminated phaser should return negative phases")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == ErrIllegalState
+		}()
+		NewPhaser(0).Arrive()
+		return
+	}()
+	if !r {
+		t.Fatal("arrive without parties should panic with ErrIllegalState")
+	}
+}
+
+func TestPhaser_ArriveAndAwaitAdvance(t *testing.T) {
+	p := NewPhaser(0)
+	p.SetOnAdvance(func(phase, registeredParties int) bool {
+		return phase == 4 || registeredParties == 0
+	})
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	counts := make([]int, 5)
+	for g := 0; g < 4; g++ {
+		p.Register()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for phase := 0; ; phase++ {
+				mu.Lock()
+				counts[phase]++
+				mu.Unlock()
+				next := p.ArriveAndAwaitAdvance()
+				if next < 0 {
+					return
+				}
+				mu.Lock()
+				if counts[phase] != 4 {
+					t.Error("party left the phase early")
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if !p.IsTerminated() || counts[4] != 4 {
+		t.Fatal("phaser should terminate after phase 4")
+	}
+}
+
+func TestPhaser_Tiered(t *testing.T) {
+	root := NewPhaser(0)
+	children := []*Phaser{NewTieredPhaser(root, 2), NewTieredPhaser(root, 0), NewTieredPhaser(root, 3)}
+	if root.GetRegisteredParties() != 2 || children[0].GetRoot() != root || children[1].GetParent() != root {
+		t.Fatal("children with parties should register with the root")
+	}
+	var wg sync.WaitGroup
+	for _, c := range children {
+		for i := 0; i < c.GetRegisteredParties(); i++ {
+			wg.Add(1)
+			go func(c *Phaser) {
+				defer wg.Done()
+				for phase := 0; phase < 3; phase++ {
+					if c.ArriveAndAwaitAdvance() != phase+1 {
+						t.Error("tiered phase error")
+					}
+				}
+				c.ArriveAndDeregister()
+			}(c)
+		}
+	}
+	wg.Wait()
+	if !root.IsTerminated() || children[2].GetPhase() != root.GetPhase() {
+		t.Fatal("tree should terminate once all parties deregistered")
+	}
+
+	root = NewPhaser(1)
+	child := NewTieredPhaser(root, 0)
+	if child.Register() != 0 || root.GetRegisteredParties() != 2 {
+		t.Fatal("first party of a child should register the child")
+	}
+	child.Arrive()
+	if root.GetPhase() != 0 || root.Arrive() != 0 || child.GetPhase() != 1 || child.GetUnarrivedParties() != 1 {
+		t.Fatal("child should advance with the root")
+	}
+	child.ForceTermination()
+	if !root.IsTerminated() {
+		t.Fatal("termination should reach the root")
+	}
+
+	root = NewPhaser(1)
+	child = NewTieredPhaser(root, 1)
+	child.Arrive()
+	registered := make(chan int)
+	go func() {
+		registered <- child.Register()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-registered:
+		t.Fatal("register on an arrived child should wait for the root to advance")
+	default:
+	}
+	if root.Arrive() != 0 || <-registered != 1 {
+		t.Fatal("party should be registered in the next phase")
+	}
+	if child.Arrive() != 1 || root.GetPhase() != 1 || root.GetUnarrivedParties() != 2 {
+		t.Fatal("child should arrive at the root once per phase")
+	}
+}
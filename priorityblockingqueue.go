@@ -1,77 +1,223 @@
 package guc
 
 import (
+	"iter"
 	"math"
 	"sync"
 	"time"
-	"unsafe"
 )
 
 var _ BlockingQueue = new(PriorityBlockingQueue)
 
+// OverflowPolicy decides what a bounded PriorityBlockingQueue does
+// with a new element when it is full
+type OverflowPolicy int
+
+const (
+	// the incoming element is rejected, Put blocks until there is room
+	OverflowReject OverflowPolicy = iota
+	// the element with the lowest priority among the queued elements and
+	// the incoming one is discarded, inserting never blocks
+	OverflowEvictLowest
+)
+
 type PriorityBlockingQueue struct {
+	AbstractQueue
 	lock          sync.Mutex
 	priorityQueue PriorityQueue
-	cond          *sync.Cond
+	notEmpty      *Condition
+	notFull       *Condition
 
-	hashCode int
+	// 0 means unbounded
+	capacity int
+	overflow OverflowPolicy
 }
 
 func NewPriorityBlockingQueue() *PriorityBlockingQueue {
-	queue := &PriorityBlockingQueue{
-		cond: sync.NewCond(new(sync.Mutex)),
-	}
+	queue := &PriorityBlockingQueue{}
+	queue.initConds()
 	return queue
 }
 
 func NewPriorityBlockingQueueWithComparator(comparator Comparator) *PriorityBlockingQueue {
-	queue := &PriorityBlockingQueue{
-		cond: sync.NewCond(new(sync.Mutex)),
-	}
+	queue := &PriorityBlockingQueue{}
+	queue.initConds()
 	queue.priorityQueue.data.comparator = comparator
 	return queue
 }
 
-type priorityBlockingQueueIter struct {
-	idx   int
-	data  []interface{}
-	queue *PriorityBlockingQueue
+// NewStablePriorityBlockingQueue creates a queue which returns elements of
+// equal priority in insertion order, see NewStablePriority
+func NewStablePriorityBlockingQueue(comparator Comparator) *PriorityBlockingQueue {
+	queue := NewPriorityBlockingQueueWithComparator(comparator)
+	queue.priorityQueue.data.stable = true
+	return queue
 }
 
-func (this *priorityBlockingQueueIter) HasNext() bool {
-	this.idx++
-	return this.idx < len(this.data)
+// NewBoundedPriorityBlockingQueue creates a queue holding at most capacity
+// elements, comparator may be nil if elements implement Comparable
+func NewBoundedPriorityBlockingQueue(capacity int, comparator Comparator) *PriorityBlockingQueue {
+	return NewBoundedPriorityBlockingQueueWithPolicy(capacity, comparator, OverflowReject)
 }
 
-func (this *priorityBlockingQueueIter) Next() interface{} {
-	r := this.data[this.idx]
-	return r
+func NewBoundedPriorityBlockingQueueWithPolicy(capacity int, comparator Comparator,
+	policy OverflowPolicy) *PriorityBlockingQueue {
+	queue, err := NewBoundedPriorityBlockingQueueChecked(capacity, comparator, policy)
+	if err != nil {
+		panic(err)
+	}
+	return queue
+}
+
+// NewBoundedPriorityBlockingQueueChecked returns ErrIllegalArgument
+// instead of panicking if capacity is not positive
+func NewBoundedPriorityBlockingQueueChecked(capacity int, comparator Comparator,
+	policy OverflowPolicy) (*PriorityBlockingQueue, error) {
+	if capacity <= 0 {
+		return nil, ErrIllegalArgument
+	}
+	queue := NewPriorityBlockingQueueWithComparator(comparator)
+	queue.capacity = capacity
+	queue.overflow = policy
+	return queue, nil
 }
 
-func (this *priorityBlockingQueueIter) Remove() {
-	this.queue.Remove(this.data[this.idx])
+func (this *PriorityBlockingQueue) initConds() {
+	this.InitAbstractQueue(this)
+	this.priorityQueue.data.queue = &this.priorityQueue
+	this.notEmpty = NewCondition(&this.lock)
+	this.notFull = NewCondition(&this.lock)
 }
 
-func (this *priorityBlockingQueueIter) ForEachRemaining(consumer func(i interface{})) {
-	for this.HasNext() {
-		consumer(this.Next())
+// caller must hold the lock
+func (this *PriorityBlockingQueue) isFull() bool {
+	return this.capacity > 0 && this.priorityQueue.Size() >= this.capacity
+}
+
+// timedWait waits on cond until it is woken up or the deadline passes,
+// caller must hold the lock. return false if the deadline already passed
+func (this *PriorityBlockingQueue) timedWait(cond *Condition, deadline time.Time) bool {
+	if !time.Now().Before(deadline) {
+		return false
 	}
+	cond.AwaitUntil(deadline)
+	return true
 }
 
-func (this *PriorityBlockingQueue) Iterator() Iterator {
-	arr := this.ToArray()
-	return &priorityBlockingQueueIter{
-		data:  arr,
-		idx:   -1,
-		queue: this,
+// makeRoom returns true if there is room for i, evicting the lowest
+// priority element if the overflow policy allows it. caller must hold the lock
+func (this *PriorityBlockingQueue) makeRoom(i interface{}) bool {
+	if !this.isFull() {
+		return true
+	}
+	if this.overflow != OverflowEvictLowest {
+		return false
+	}
+	d := &this.priorityQueue.data
+	idx := d.lowest()
+	if d.compare(i, d.data[idx]) >= 0 {
+		// the incoming element has the lowest priority itself
+		return false
+	}
+	d.removeAt(idx)
+	return true
+}
+
+// offerLocked inserts i if there is room or the overflow policy allows it,
+// caller must hold the lock
+func (this *PriorityBlockingQueue) offerLocked(i interface{}) bool {
+	if !this.makeRoom(i) {
+		return false
 	}
+	this.priorityQueue.Offer(i)
+	this.notEmpty.Signal()
+	return true
+}
+
+// PriorityBlockingHandle is the thread-safe counterpart of PriorityHandle
+type PriorityBlockingHandle struct {
+	queue  *PriorityBlockingQueue
+	handle *PriorityHandle
 }
 
-func (this *PriorityBlockingQueue) ForEach(consumer func(i interface{})) {
-	iter := this.Iterator()
-	for iter.HasNext() {
-		consumer(iter.Next())
+// Update calls mutator with the element under the queue lock, so the
+// priority can be changed safely, then restores the heap order.
+// mutator may be nil. return false if the element is not in the queue anymore
+func (this *PriorityBlockingHandle) Update(mutator func(i interface{})) bool {
+	this.queue.lock.Lock()
+	defer this.queue.lock.Unlock()
+	if this.handle.index < 0 {
+		return false
+	}
+	if mutator != nil {
+		mutator(this.handle.Value())
 	}
+	return this.handle.Update()
+}
+
+// Remove removes the element from the queue, return false if the
+// element is not in the queue anymore
+func (this *PriorityBlockingHandle) Remove() bool {
+	this.queue.lock.Lock()
+	r := this.handle.Remove()
+	if r {
+		this.queue.notFull.Signal()
+	}
+	this.queue.lock.Unlock()
+	return r
+}
+
+// Index returns the position of the element in the underlying heap,
+// -1 if the element is not in the queue anymore
+func (this *PriorityBlockingHandle) Index() int {
+	this.queue.lock.Lock()
+	idx := this.handle.Index()
+	this.queue.lock.Unlock()
+	return idx
+}
+
+// Value returns the element, nil if the element is not in the queue anymore
+func (this *PriorityBlockingHandle) Value() interface{} {
+	this.queue.lock.Lock()
+	v := this.handle.Value()
+	this.queue.lock.Unlock()
+	return v
+}
+
+// SetEquality sets the strategy used by Contains and Remove,
+// nil means DefaultEquality
+func (this *PriorityBlockingQueue) SetEquality(equality Equality) {
+	this.lock.Lock()
+	this.priorityQueue.SetEquality(equality)
+	this.lock.Unlock()
+}
+
+// Iterator returns an iterator over a snapshot of the queue, its Remove
+// removes the very element it returned
+func (this *PriorityBlockingQueue) Iterator() Iterator {
+	return &snapshotIter{data: this.ToArray(), last: -1, remove: this.removeEq}
+}
+
+// SortedIterator returns an iterator over a snapshot of the queue
+// in priority order
+func (this *PriorityBlockingQueue) SortedIterator() Iterator {
+	return &snapshotIter{data: this.ToSortedArray(), last: -1, remove: this.removeEq}
+}
+
+// All yields a snapshot of the elements in the same order as Iterator
+func (this *PriorityBlockingQueue) All() iter.Seq[any] {
+	return sliceSeq(this.ToArray())
+}
+
+// Sorted yields a snapshot of the elements in priority order
+func (this *PriorityBlockingQueue) Sorted() iter.Seq[any] {
+	return sliceSeq(this.ToSortedArray())
+}
+
+// Backward yields a snapshot of the elements from the lowest priority
+// to the highest
+func (this *PriorityBlockingQueue) Backward() iter.Seq[any] {
+	return sliceBackwardSeq(this.ToSortedArray())
 }
 
 func (this *PriorityBlockingQueue) Size() int {
@@ -86,12 +232,18 @@ func (this *PriorityBlockingQueue) IsEmpty() bool {
 }
 
 func (this *PriorityBlockingQueue) Contains(i interface{}) bool {
-	this.lock.Lock()
-	r := this.priorityQueue.Contains(i)
-	this.lock.Unlock()
+	r, _ := this.TryContains(i)
 	return r
 }
 
+// TryContains is like Contains, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *PriorityBlockingQueue) TryContains(i interface{}) (bool, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	return this.priorityQueue.TryContains(i)
+}
+
 func (this *PriorityBlockingQueue) ToArray() []interface{} {
 	this.lock.Lock()
 	data := this.priorityQueue.data.data
@@ -103,6 +255,14 @@ func (this *PriorityBlockingQueue) ToArray() []interface{} {
 	return result
 }
 
+// ToSortedArray returns all elements in priority order
+func (this *PriorityBlockingQueue) ToSortedArray() []interface{} {
+	this.lock.Lock()
+	r := this.priorityQueue.ToSortedArray()
+	this.lock.Unlock()
+	return r
+}
+
 func (this *PriorityBlockingQueue) FillArray(arr []interface{}) []interface{} {
 	this.lock.Lock()
 	data := this.priorityQueue.data.data
@@ -122,52 +282,41 @@ func (this *PriorityBlockingQueue) FillArray(arr []interface{}) []interface{} {
 	}
 }
 
-func (this *PriorityBlockingQueue) Add(i interface{}) bool {
-	return this.Offer(i)
-}
-
 func (this *PriorityBlockingQueue) Remove(i interface{}) bool {
-	this.lock.Lock()
-	r := this.priorityQueue.Remove(i)
-	this.lock.Unlock()
+	r, _ := this.TryRemove(i)
 	return r
 }
 
-func (this *PriorityBlockingQueue) ContainsAll(coll Collection) bool {
-	iter := coll.Iterator()
-	for iter.HasNext() {
-		if !this.Contains(iter.Next()) {
-			return false
-		}
-	}
-	return true
+// TryRemove is like Remove, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *PriorityBlockingQueue) TryRemove(i interface{}) (bool, error) {
+	return this.remove((*PriorityQueue).TryRemove, i)
 }
 
-func (this *PriorityBlockingQueue) AddAll(coll Collection) bool {
-	changed := false
-	iter := coll.Iterator()
-	for iter.HasNext() {
-		changed = true
-		this.Add(iter.Next())
-	}
-	return changed
+// removeEq is like Remove but matches the element by identity
+func (this *PriorityBlockingQueue) removeEq(i interface{}) bool {
+	r, _ := this.remove(func(q *PriorityQueue, i interface{}) (bool, error) {
+		return q.removeEq(i), nil
+	}, i)
+	return r
 }
 
-func (this *PriorityBlockingQueue) RemoveAll(coll Collection) bool {
-	removed := false
-	iter := coll.Iterator()
-	for iter.HasNext() {
-		r := this.Remove(iter.Next())
-		if r {
-			removed = true
-		}
+func (this *PriorityBlockingQueue) remove(remove func(*PriorityQueue, interface{}) (bool, error), i interface{}) (bool, error) {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	r, err := remove(&this.priorityQueue, i)
+	if r {
+		this.notFull.Signal()
 	}
-	return removed
+	return r, err
 }
 
 func (this *PriorityBlockingQueue) RemoveIf(predicate func(i interface{}) bool) bool {
 	this.lock.Lock()
 	r := this.priorityQueue.RemoveIf(predicate)
+	if r {
+		this.notFull.Signal()
+	}
 	this.lock.Unlock()
 	return r
 }
@@ -175,6 +324,9 @@ func (this *PriorityBlockingQueue) RemoveIf(predicate func(i interface{}) bool)
 func (this *PriorityBlockingQueue) RetainAll(coll Collection) bool {
 	this.lock.Lock()
 	r := this.priorityQueue.RetainAll(coll)
+	if r {
+		this.notFull.SignalAll()
+	}
 	this.lock.Unlock()
 	return r
 }
@@ -182,60 +334,52 @@ func (this *PriorityBlockingQueue) RetainAll(coll Collection) bool {
 func (this *PriorityBlockingQueue) Clear() {
 	this.lock.Lock()
 	this.priorityQueue.Clear()
+	this.notFull.SignalAll()
 	this.lock.Unlock()
 }
 
-func (this *PriorityBlockingQueue) Equals(i interface{}) bool {
-	p, ok := i.(*PriorityBlockingQueue)
-	if ok {
-		return p == this
-	}
-	return false
-}
-
-func (this *PriorityBlockingQueue) HashCode() int {
-	hashCode := this.hashCode
-	if hashCode != 0 {
-		return hashCode
-	}
-	hashCode = int(uintptr(unsafe.Pointer(this)))
-	this.hashCode = hashCode
-	return hashCode
-}
-
 func (this *PriorityBlockingQueue) Offer(i interface{}) bool {
 	this.lock.Lock()
-	r := this.priorityQueue.Offer(i)
-	// notify other goroutines which are waiting on this Cond to take
-	this.cond.Broadcast()
+	r := this.offerLocked(i)
 	this.lock.Unlock()
 	return r
 }
 
-func (this *PriorityBlockingQueue) RemoveHead() interface{} {
-	i := this.Poll()
+// OfferWithHandle inserts i and returns a handle to update or remove it
+// later, return nil if a bounded queue has no room for it
+func (this *PriorityBlockingQueue) OfferWithHandle(i interface{}) *PriorityBlockingHandle {
+	this.lock.Lock()
+	defer this.lock.Unlock()
+	if !this.makeRoom(i) {
+		return nil
+	}
+	h := this.priorityQueue.OfferWithHandle(i)
+	this.notEmpty.Signal()
+	return &PriorityBlockingHandle{queue: this, handle: h}
+}
+
+// TryAdd inserts i without blocking, return ErrNilElement if i is nil,
+// ErrFull if a bounded queue has no room for it
+func (this *PriorityBlockingQueue) TryAdd(i interface{}) error {
 	if i == nil {
-		panic("queue is empty")
+		return ErrNilElement
 	}
-	return i
+	if !this.Offer(i) {
+		return ErrFull
+	}
+	return nil
 }
 
 func (this *PriorityBlockingQueue) Poll() interface{} {
 	this.lock.Lock()
 	i := this.priorityQueue.Poll()
+	if i != nil {
+		this.notFull.Signal()
+	}
 	this.lock.Unlock()
 	return i
 }
 
-func (this *PriorityBlockingQueue) Element() interface{} {
-	p := this.Peek()
-	if p != nil {
-		return p
-	} else {
-		panic("queue is empty")
-	}
-}
-
 func (this *PriorityBlockingQueue) Peek() interface{} {
 	this.lock.Lock()
 	p := this.priorityQueue.Peek()
@@ -244,32 +388,67 @@ func (this *PriorityBlockingQueue) Peek() interface{} {
 }
 
 func (this *PriorityBlockingQueue) Put(i interface{}) {
-	this.Offer(i)
+	this.lock.Lock()
+	if this.overflow != OverflowEvictLowest {
+		for this.isFull() {
+			this.notFull.Await()
+		}
+	}
+	this.offerLocked(i)
+	this.lock.Unlock()
 }
 
 func (this *PriorityBlockingQueue) OfferWithTimeout(i interface{}, t time.Duration) bool {
-	return this.Offer(i)
+	deadline := time.Now().Add(t)
+	this.lock.Lock()
+	if this.overflow != OverflowEvictLowest {
+		for this.isFull() {
+			if !this.timedWait(this.notFull, deadline) {
+				this.lock.Unlock()
+				return false
+			}
+		}
+	}
+	r := this.offerLocked(i)
+	this.lock.Unlock()
+	return r
 }
 
 func (this *PriorityBlockingQueue) Take() interface{} {
-	for {
-		i := this.Poll()
-		if i != nil {
-			return i
-		}
-		this.cond.L.Lock()
-		this.cond.Wait()
-		this.cond.L.Unlock()
+	this.lock.Lock()
+	for this.priorityQueue.IsEmpty() {
+		this.notEmpty.Await()
 	}
+	i := this.priorityQueue.Poll()
+	this.notFull.Signal()
+	this.lock.Unlock()
+	return i
 }
 
+// PollWithTimeout returns nil if no element is available within t
 func (this *PriorityBlockingQueue) PollWithTimeout(t time.Duration) interface{} {
-	//FIXME need to wait for the given time
-	return this.Take()
+	deadline := time.Now().Add(t)
+	this.lock.Lock()
+	for this.priorityQueue.IsEmpty() {
+		if !this.timedWait(this.notEmpty, deadline) {
+			this.lock.Unlock()
+			return nil
+		}
+	}
+	i := this.priorityQueue.Poll()
+	this.notFull.Signal()
+	this.lock.Unlock()
+	return i
 }
 
 func (this *PriorityBlockingQueue) RemainingCapacity() int {
-	return math.MaxInt32
+	if this.capacity <= 0 {
+		return math.MaxInt32
+	}
+	this.lock.Lock()
+	r := this.capacity - this.priorityQueue.Size()
+	this.lock.Unlock()
+	return r
 }
 
 func (this *PriorityBlockingQueue) DrainTo(coll Collection) int {
@@ -286,6 +465,9 @@ func (this *PriorityBlockingQueue) DrainToWithLimit(coll Collection, total int)
 	for i := 0; i < max; i++ {
 		coll.Add(q.Poll())
 	}
+	if max > 0 {
+		this.notFull.SignalAll()
+	}
 	this.lock.Unlock()
 	return max
 }
@@ -335,8 +335,18 @@ func TestPriorityBlockingQueue_Equals(t *testing.T) {
 	if !p.Equals(p) {
 		t.Fatal("queue should be equals to itself")
 	}
-	if p.Equals(newPreparedPriorityBlockingQueue()) {
-		t.Fatal("queue should not be equals to a new one")
+	other := newPreparedPriorityBlockingQueue()
+	if !p.Equals(other) || p.HashCode() != other.HashCode() {
+		t.Fatal("queue should be equals to a queue with the same elements")
+	}
+	other.Poll()
+	if p.Equals(other) || other.Equals(p) {
+		t.Fatal("queue should not be equals to a queue with other elements")
+	}
+	q := NewPriority()
+	q.AddAll(p)
+	if !p.Equals(q) || !q.Equals(p) {
+		t.Fatal("queue should be equals to any queue with the same elements")
 	}
 	if p.Equals(struct{}{}) {
 		t.Fatal("queue should not be equals to another object of other type")
@@ -600,8 +610,9 @@ func TestPriorityBlockingQueueIter_Remove(t *testing.T) {
 	p := newPreparedPriorityBlockingQueue()
 	iter := p.Iterator()
 	iter.HasNext()
+	iter.Next()
 	iter.Remove()
-	iterImpl := iter.(*priorityBlockingQueueIter)
+	iterImpl := iter.(*snapshotIter)
 	if len(iterImpl.data) != 7 {
 		t.Fatal("iter size should be 7")
 	}
@@ -609,3 +620,211 @@ func TestPriorityBlockingQueueIter_Remove(t *testing.T) {
 		t.Fatal("iter size should be 6")
 	}
 }
+
+func TestPriorityBlockingQueueIter_RemoveByIdentity(t *testing.T) {
+	for _, sorted := range []bool{false, true} {
+		a, b := newSampleBlockingItem(1), newSampleBlockingItem(1)
+		p := NewPriorityBlockingQueue()
+		p.Add(a)
+		p.Add(b)
+		iter := p.Iterator()
+		if sorted {
+			iter = p.SortedIterator()
+		}
+		for iter.HasNext() {
+			if iter.Next() == b {
+				iter.Remove()
+			}
+		}
+		if p.Size() != 1 || p.Peek() != a {
+			t.Fatal("iterator remove should remove the returned item, not an equal one")
+		}
+	}
+}
+
+func TestNewBoundedPriorityBlockingQueue(t *testing.T) {
+	p := NewBoundedPriorityBlockingQueue(2, nil)
+	if p.RemainingCapacity() != 2 {
+		t.Fatal("remaining capacity should be 2")
+	}
+	if !p.Offer(newSampleBlockingItem(1)) || !p.Offer(newSampleBlockingItem(2)) {
+		t.Fatal("offer should succeed while not full")
+	}
+	if p.RemainingCapacity() != 0 {
+		t.Fatal("remaining capacity should be 0")
+	}
+	if p.Offer(newSampleBlockingItem(0)) {
+		t.Fatal("offer should fail when full")
+	}
+	if p.Size() != 2 {
+		t.Fatal("queue size should be 2")
+	}
+
+	r := func() (result bool) {
+		defer func() {
+			result = recover() != nil
+		}()
+		NewBoundedPriorityBlockingQueue(0, nil)
+		return
+	}()
+	if !r {
+		t.Fatal("should panic with zero capacity")
+	}
+}
+
+func TestBoundedPriorityBlockingQueue_Put(t *testing.T) {
+	p := NewBoundedPriorityBlockingQueue(1, nil)
+	p.Put(newSampleBlockingItem(1))
+	ch := make(chan struct{})
+	go func() {
+		p.Put(newSampleBlockingItem(2))
+		close(ch)
+	}()
+	select {
+	case <-ch:
+		t.Fatal("put should block when full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if p.Take().(*sampleBlockingItem).Value != 1 {
+		t.Fatal("should take value 1")
+	}
+	select {
+	case <-ch:
+	case <-time.After(1 * time.Second):
+		t.Fatal("put should be unblocked by take")
+	}
+	if p.Take().(*sampleBlockingItem).Value != 2 {
+		t.Fatal("should take value 2")
+	}
+}
+
+func TestBoundedPriorityBlockingQueue_OfferWithTimeout(t *testing.T) {
+	p := NewBoundedPriorityBlockingQueue(1, nil)
+	p.Put(newSampleBlockingItem(1))
+	begin := time.Now()
+	if p.OfferWithTimeout(newSampleBlockingItem(2), 50*time.Millisecond) {
+		t.Fatal("offer should time out when full")
+	}
+	if time.Since(begin) < 50*time.Millisecond {
+		t.Fatal("offer should wait until the deadline")
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		p.Poll()
+	}()
+	if !p.OfferWithTimeout(newSampleBlockingItem(2), 1*time.Second) {
+		t.Fatal("offer should succeed after poll")
+	}
+	if p.Peek().(*sampleBlockingItem).Value != 2 {
+		t.Fatal("head should be value 2")
+	}
+}
+
+func TestBoundedPriorityBlockingQueue_EvictLowest(t *testing.T) {
+	p := NewBoundedPriorityBlockingQueueWithPolicy(3, nil, OverflowEvictLowest)
+	p.Put(newSampleBlockingItem(5))
+	p.Put(newSampleBlockingItem(3))
+	p.Put(newSampleBlockingItem(7))
+	if p.Offer(newSampleBlockingItem(9)) {
+		t.Fatal("lowest priority incoming item should be rejected")
+	}
+	if !p.Offer(newSampleBlockingItem(1)) {
+		t.Fatal("higher priority incoming item should be accepted")
+	}
+	p.Put(newSampleBlockingItem(2))
+	if p.Size() != 3 {
+		t.Fatal("queue size should be 3")
+	}
+	for _, v := range []int{1, 2, 3} {
+		if p.Poll().(*sampleBlockingItem).Value != v {
+			t.Fatal("should poll value", v)
+		}
+	}
+}
+
+func TestNewStablePriorityBlockingQueue(t *testing.T) {
+	p := NewStablePriorityBlockingQueue(sampleJobComparator{})
+	for i := 0; i < 100; i++ {
+		p.Put(&sampleJob{priority: i % 3, id: i})
+	}
+	d := NewPriorityWithComparator(sampleJobComparator{})
+	if p.DrainToWithLimit(d, 10) != 10 {
+		t.Fatal("drain result should be 10")
+	}
+	lastId := -1
+	for !p.IsEmpty() {
+		job := p.Take().(*sampleJob)
+		if job.priority == 0 {
+			if job.id < lastId {
+				t.Fatal("equal priority jobs should come out in insertion order")
+			}
+			lastId = job.id
+		}
+	}
+}
+
+func TestPriorityBlockingQueue_OfferWithHandle(t *testing.T) {
+	p := newPreparedPriorityBlockingQueue()
+	h := p.OfferWithHandle(newSampleBlockingItem(50))
+	if !h.Update(func(i interface{}) {
+		i.(*sampleBlockingItem).Value = 1
+	}) {
+		t.Fatal("update result should be true")
+	}
+	if h.Index() != 0 {
+		t.Fatal("updated element should be the head")
+	}
+	if !h.Remove() {
+		t.Fatal("remove result should be true")
+	}
+	if h.Remove() || h.Update(nil) || h.Value() != nil {
+		t.Fatal("handle should be invalid after remove")
+	}
+	if p.Size() != 7 {
+		t.Fatal("queue size should be 7")
+	}
+
+	b := NewBoundedPriorityBlockingQueue(1, nil)
+	if b.OfferWithHandle(newSampleBlockingItem(1)) == nil {
+		t.Fatal("handle should not be nil")
+	}
+	if b.OfferWithHandle(newSampleBlockingItem(2)) != nil {
+		t.Fatal("handle should be nil when full")
+	}
+
+	e := NewBoundedPriorityBlockingQueueWithPolicy(2, nil, OverflowEvictLowest)
+	h5 := e.OfferWithHandle(newSampleBlockingItem(5))
+	h3 := e.OfferWithHandle(newSampleBlockingItem(3))
+	if e.OfferWithHandle(newSampleBlockingItem(9)) != nil {
+		t.Fatal("lowest priority incoming item should be rejected")
+	}
+	h1 := e.OfferWithHandle(newSampleBlockingItem(1))
+	if h1 == nil {
+		t.Fatal("higher priority incoming item should evict the lowest")
+	}
+	if e.Size() != 2 || h5.Value() != nil || h5.Remove() {
+		t.Fatal("handle of the evicted item should be invalid")
+	}
+	if h3.Value().(*sampleBlockingItem).Value != 3 || h1.Index() != 0 {
+		t.Fatal("handles of the remaining items should stay valid")
+	}
+}
+
+func TestPriorityBlockingQueue_ToSortedArray(t *testing.T) {
+	p := newPreparedPriorityBlockingQueue()
+	arr := p.ToSortedArray()
+	for i := 1; i < len(arr); i++ {
+		if arr[i-1].(*sampleBlockingItem).Value > arr[i].(*sampleBlockingItem).Value {
+			t.Fatal("array should be sorted")
+		}
+	}
+	iter := p.SortedIterator()
+	if !iter.HasNext() || iter.Next().(*sampleBlockingItem).Value != 2 {
+		t.Fatal("first element should be value 2")
+	}
+	iter.Remove()
+	if p.Size() != 6 {
+		t.Fatal("queue size should be 6")
+	}
+}
@@ -2,7 +2,8 @@ package guc
 
 import (
 	"container/heap"
-	"unsafe"
+	"iter"
+	"sort"
 )
 
 var _ Queue = new(PriorityQueue)
@@ -13,34 +14,170 @@ type priorityData struct {
 	data       []interface{}
 	queue      *PriorityQueue
 	comparator Comparator
+
+	// in stable mode every element gets an insertion sequence number,
+	// kept in seqs in parallel with data, to break ties in FIFO order
+	stable  bool
+	seqs    []uint64
+	nextSeq uint64
+
+	// lazily created on the first OfferWithHandle, kept in parallel with
+	// data, nil for the elements offered without handle
+	handles []*PriorityHandle
+
+	// number of structural modifications, iterators fail fast when it changes
+	modCount int
 }
 
 type PriorityQueue struct {
 	data priorityData
+	// nil means DefaultEquality
+	equality Equality
+}
 
-	hashCode int
+// PriorityHandle refers to an element offered by OfferWithHandle,
+// it keeps track of the element position so that the element can be
+// re-ordered or removed in O(log n)
+type PriorityHandle struct {
+	queue *PriorityQueue
+	// -1 when the element is not in the queue anymore
+	index int
+}
+
+// Update restores the heap order after the caller changed the priority of
+// the element, return false if the element is not in the queue anymore
+func (this *PriorityHandle) Update() bool {
+	if this.index < 0 {
+		return false
+	}
+	heap.Fix(&this.queue.data, this.index)
+	this.queue.data.modCount++
+	return true
+}
+
+// Remove removes the element from the queue, return false if the
+// element is not in the queue anymore
+func (this *PriorityHandle) Remove() bool {
+	if this.index < 0 {
+		return false
+	}
+	heap.Remove(&this.queue.data, this.index)
+	return true
+}
+
+// Index returns the position of the element in the underlying heap,
+// -1 if the element is not in the queue anymore
+func (this *PriorityHandle) Index() int {
+	return this.index
+}
+
+// Value returns the element, nil if the element is not in the queue anymore
+func (this *PriorityHandle) Value() interface{} {
+	if this.index < 0 {
+		return nil
+	}
+	return this.queue.data.data[this.index]
 }
 
+// snapshotIter iterates over a snapshot of a collection,
+// Remove deletes the last returned element from the collection
+// with remove, queues match it by identity
+type snapshotIter struct {
+	data   []interface{}
+	cursor int
+	last   int
+	remove func(i interface{}) bool
+}
+
+func (this *snapshotIter) HasNext() bool {
+	return this.cursor < len(this.data)
+}
+
+func (this *snapshotIter) Next() interface{} {
+	r := this.data[this.cursor]
+	this.last = this.cursor
+	this.cursor++
+	return r
+}
+
+func (this *snapshotIter) Remove() {
+	if this.last < 0 {
+		panic("no element to remove")
+	}
+	this.remove(this.data[this.last])
+	this.last = -1
+}
+
+func (this *snapshotIter) ForEachRemaining(consumer func(i interface{})) {
+	for this.HasNext() {
+		consumer(this.Next())
+	}
+}
+
+// priorityQueueIter is fail-fast: Next and Remove panic with
+// ErrConcurrentModification if the queue was modified by other means
 type priorityQueueIter struct {
-	idx   int
-	data  []interface{}
-	queue *PriorityQueue
+	// index of the next element
+	idx int
+	// index of the element returned by the last Next, -1 if none
+	// or if it was taken from forgetMeNot
+	lastRet int
+	// the element returned by the last Next if it was taken from forgetMeNot
+	lastRetElt interface{}
+	// elements moved from the unvisited part of the heap to the visited
+	// part by Remove, they are returned after the heap is exhausted
+	forgetMeNot      []interface{}
+	expectedModCount int
+	queue            *PriorityQueue
+}
+
+func (this *priorityQueueIter) checkModCount() {
+	if this.queue.data.modCount != this.expectedModCount {
+		panic(ErrConcurrentModification)
+	}
 }
 
 func (this *priorityQueueIter) HasNext() bool {
-	this.idx++
-	return this.idx < len(this.data)
+	return this.idx < len(this.queue.data.data) || len(this.forgetMeNot) > 0
 }
 
 func (this *priorityQueueIter) Next() interface{} {
-	r := this.data[this.idx]
-	return r
+	this.checkModCount()
+	data := this.queue.data.data
+	if this.idx < len(data) {
+		this.lastRet = this.idx
+		this.idx++
+		return data[this.lastRet]
+	}
+	if len(this.forgetMeNot) > 0 {
+		this.lastRet = -1
+		this.lastRetElt = this.forgetMeNot[0]
+		this.forgetMeNot[0] = nil
+		this.forgetMeNot = this.forgetMeNot[1:]
+		return this.lastRetElt
+	}
+	panic("no more elements")
 }
 
 func (this *priorityQueueIter) Remove() {
-	heap.Remove(&this.queue.data, this.idx)
-	this.data = this.queue.data.data
-	this.idx--
+	this.checkModCount()
+	if this.lastRet >= 0 {
+		moved, movedUp := this.queue.data.removeAt(this.lastRet)
+		if movedUp {
+			this.forgetMeNot = append(this.forgetMeNot, moved)
+		} else {
+			// the slot now holds an element which is not visited yet
+			this.idx = this.lastRet
+		}
+		this.lastRet = -1
+	} else if this.lastRetElt != nil {
+		// an equal element may sit before it, remove this very one
+		this.queue.removeEq(this.lastRetElt)
+		this.lastRetElt = nil
+	} else {
+		panic("no element to remove")
+	}
+	this.expectedModCount = this.queue.data.modCount
 }
 
 func (this *priorityQueueIter) ForEachRemaining(consumer func(i interface{})) {
@@ -67,57 +204,234 @@ func NewPriorityWithComparator(comparator Comparator) *PriorityQueue {
 	return queue
 }
 
+// NewPriorityFromCollection creates a queue holding all elements of coll,
+// the heap is built in O(n). comparator may be nil if elements implement Comparable
+func NewPriorityFromCollection(coll Collection, comparator Comparator) *PriorityQueue {
+	return NewPriorityFromSlice(coll.ToArray(), comparator)
+}
+
+// NewPriorityFromSlice creates a queue holding a copy of arr,
+// the heap is built in O(n). comparator may be nil if elements implement Comparable
+func NewPriorityFromSlice(arr []interface{}, comparator Comparator) *PriorityQueue {
+	queue := NewPriorityWithComparator(comparator)
+	queue.data.data = make([]interface{}, len(arr))
+	copy(queue.data.data, arr)
+	heap.Init(&queue.data)
+	return queue
+}
+
+// NewStablePriority creates a queue which returns elements of equal priority
+// in insertion order, comparator may be nil if elements implement Comparable
+func NewStablePriority(comparator Comparator) *PriorityQueue {
+	queue := NewPriorityWithComparator(comparator)
+	queue.data.stable = true
+	return queue
+}
+
 func (this priorityData) Len() int {
 	return len(this.data)
 }
 
 func (this priorityData) Less(i, j int) bool {
-	c := this.comparator
-	data := this.data
+	c := this.compare(this.data[i], this.data[j])
+	if c == 0 && this.stable {
+		return this.seqs[i] < this.seqs[j]
+	}
+	return c < 0
+}
+
+func (this priorityData) compare(o1, o2 interface{}) int {
+	return compare(this.comparator, o1, o2)
+}
+
+// compare orders two elements using the comparator if present,
+// otherwise the elements must implement Comparable
+func compare(c Comparator, o1, o2 interface{}) int {
 	if c != nil {
-		if c.Compare(data[i], data[j]) < 0 {
-			return true
-		} else {
-			return false
-		}
+		return c.Compare(o1, o2)
 	} else {
-		if data[i].(Comparable).CompareTo(data[j]) < 0 {
-			return true
-		} else {
-			return false
+		return o1.(Comparable).CompareTo(o2)
+	}
+}
+
+// lowest returns the index of the element with the lowest priority,
+// which is always one of the leaves of the heap
+func (this priorityData) lowest() int {
+	n := len(this.data)
+	if n == 0 {
+		return -1
+	}
+	idx := n / 2
+	for i := idx + 1; i < n; i++ {
+		if this.Less(idx, i) {
+			idx = i
 		}
 	}
+	return idx
 }
 
 func (this priorityData) Swap(i, j int) {
 	data := this.data
 	data[j], data[i] = data[i], data[j]
+	if this.stable {
+		seqs := this.seqs
+		seqs[j], seqs[i] = seqs[i], seqs[j]
+	}
+	if this.handles != nil {
+		handles := this.handles
+		handles[j], handles[i] = handles[i], handles[j]
+		if handles[i] != nil {
+			handles[i].index = i
+		}
+		if handles[j] != nil {
+			handles[j].index = j
+		}
+	}
 }
 
 func (this *priorityData) Push(x interface{}) {
+	this.modCount++
 	this.data = append(this.data, x)
+	if this.stable {
+		this.seqs = append(this.seqs, this.nextSeq)
+		this.nextSeq++
+	}
+	if this.handles != nil {
+		this.handles = append(this.handles, nil)
+	}
 }
 
 func (this *priorityData) Pop() interface{} {
+	this.modCount++
 	old := this.data
 	n := len(old)
 	i := old[n-1]
 	old[n-1] = nil //clear index, in order to avoid memory leak
 	this.data = old[:n-1]
+	if this.stable {
+		this.seqs = this.seqs[:n-1]
+	}
+	if this.handles != nil {
+		this.invalidateHandle(n - 1)
+		this.handles = this.handles[:n-1]
+	}
 	return i
 }
 
+func (this *priorityData) invalidateHandle(idx int) {
+	h := this.handles[idx]
+	if h != nil {
+		h.index = -1
+		this.handles[idx] = nil
+	}
+}
+
+// removeAt removes the element at i like heap.Remove. if the last element,
+// which fills the slot, is sifted up before i, it returns it and true
+func (this *priorityData) removeAt(i int) (interface{}, bool) {
+	n := len(this.data) - 1
+	var moved interface{}
+	movedUp := false
+	if n != i {
+		this.Swap(i, n)
+		if !this.down(i, n) {
+			if j := this.up(i); j != i {
+				moved, movedUp = this.data[j], true
+			}
+		}
+	}
+	this.Pop()
+	return moved, movedUp
+}
+
+// same as container/heap, but up returns the final position
+func (this priorityData) up(j int) int {
+	for {
+		i := (j - 1) / 2 // parent
+		if i == j || !this.Less(j, i) {
+			break
+		}
+		this.Swap(i, j)
+		j = i
+	}
+	return j
+}
+
+func (this priorityData) down(i0, n int) bool {
+	i := i0
+	for {
+		j1 := 2*i + 1
+		if j1 >= n || j1 < 0 { // j1 < 0 after int overflow
+			break
+		}
+		j := j1 // left child
+		if j2 := j1 + 1; j2 < n && this.Less(j2, j1) {
+			j = j2 // = 2*i + 2  // right child
+		}
+		if !this.Less(j, i) {
+			break
+		}
+		this.Swap(i, j)
+		i = j
+	}
+	return i > i0
+}
+
+// SetEquality sets the strategy used by Contains and Remove,
+// nil means DefaultEquality
+func (this *PriorityQueue) SetEquality(equality Equality) {
+	this.equality = equality
+}
+
 func (this *PriorityQueue) Iterator() Iterator {
 	iter := new(priorityQueueIter)
-	iter.idx = -1
-	iter.data = this.data.data
+	iter.lastRet = -1
+	iter.expectedModCount = this.data.modCount
 	iter.queue = this
 	return iter
 }
 
+// SortedIterator returns an iterator over a snapshot of the queue
+// in priority order, unlike Iterator which follows the heap layout
+func (this *PriorityQueue) SortedIterator() Iterator {
+	return &snapshotIter{data: this.ToSortedArray(), last: -1, remove: this.removeEq}
+}
+
+// All yields the elements in the same order as Iterator, it panics with
+// ErrConcurrentModification if the queue is modified during the loop
+func (this *PriorityQueue) All() iter.Seq[any] {
+	return func(yield func(any) bool) {
+		modCount := this.data.modCount
+		for _, v := range this.data.data {
+			if !yield(v) {
+				return
+			}
+			if this.data.modCount != modCount {
+				panic(ErrConcurrentModification)
+			}
+		}
+	}
+}
+
+// Sorted yields a snapshot of the elements in priority order
+func (this *PriorityQueue) Sorted() iter.Seq[any] {
+	return sliceSeq(this.ToSortedArray())
+}
+
+// Backward yields a snapshot of the elements from the lowest priority
+// to the highest
+func (this *PriorityQueue) Backward() iter.Seq[any] {
+	return sliceBackwardSeq(this.ToSortedArray())
+}
+
+// ForEach panics with ErrConcurrentModification if consumer modifies the queue
 func (this *PriorityQueue) ForEach(consumer func(i interface{})) {
+	modCount := this.data.modCount
 	for _, v := range this.data.data {
 		consumer(v)
+		if this.data.modCount != modCount {
+			panic(ErrConcurrentModification)
+		}
 	}
 }
 
@@ -130,13 +444,31 @@ func (this *PriorityQueue) IsEmpty() bool {
 }
 
 func (this *PriorityQueue) Contains(i interface{}) bool {
-	data := this.data.data
-	for _, v := range data {
-		if v.(Object).Equals(i) {
-			return true
+	idx, _ := this.indexOf(i)
+	return idx >= 0
+}
+
+// TryContains is like Contains, it returns ErrIncomparable if i isn't
+// found and couldn't be compared with some element
+func (this *PriorityQueue) TryContains(i interface{}) (bool, error) {
+	idx, err := this.indexOf(i)
+	return idx >= 0, err
+}
+
+// indexOf returns the index of an element equal to i, or -1 and the first
+// error of the equality. incomparable elements are skipped
+func (this *PriorityQueue) indexOf(i interface{}) (int, error) {
+	var firstErr error
+	for idx, v := range this.data.data {
+		r, err := tryEqual(this.equality, v, i)
+		if r && err == nil {
+			return idx, nil
+		}
+		if firstErr == nil {
+			firstErr = err
 		}
 	}
-	return false
+	return -1, firstErr
 }
 
 func (this *PriorityQueue) ToArray() []interface{} {
@@ -148,6 +480,22 @@ func (this *PriorityQueue) ToArray() []interface{} {
 	return result
 }
 
+// ToSortedArray returns all elements in priority order, O(n log n)
+func (this *PriorityQueue) ToSortedArray() []interface{} {
+	d := this.data
+	sorted := priorityData{
+		data:       this.ToArray(),
+		comparator: d.comparator,
+		stable:     d.stable,
+	}
+	if d.stable {
+		sorted.seqs = make([]uint64, len(d.seqs))
+		copy(sorted.seqs, d.seqs)
+	}
+	sort.Sort(sorted)
+	return sorted.data
+}
+
 func (this *PriorityQueue) FillArray(arr []interface{}) []interface{} {
 	data := this.data.data
 	if len(arr) >= len(data) {
@@ -166,8 +514,25 @@ func (this *PriorityQueue) Add(i interface{}) bool {
 }
 
 func (this *PriorityQueue) Remove(item interface{}) bool {
+	r, _ := this.TryRemove(item)
+	return r
+}
+
+// TryRemove is like Remove, it returns ErrIncomparable if item isn't
+// found and couldn't be compared with some element
+func (this *PriorityQueue) TryRemove(item interface{}) (bool, error) {
+	idx, err := this.indexOf(item)
+	if idx < 0 {
+		return false, err
+	}
+	heap.Remove(&this.data, idx)
+	return true, nil
+}
+
+// removeEq is like Remove but matches the element by identity
+func (this *PriorityQueue) removeEq(item interface{}) bool {
 	for i, v := range this.data.data {
-		if v.(Object).Equals(item) {
+		if identical(this.equality, v, item) {
 			heap.Remove(&this.data, i)
 			return true
 		}
@@ -186,13 +551,22 @@ func (this *PriorityQueue) ContainsAll(coll Collection) bool {
 }
 
 func (this *PriorityQueue) AddAll(coll Collection) bool {
-	changed := false
-	iter := coll.Iterator()
-	for iter.HasNext() {
-		changed = true
-		heap.Push(&this.data, iter.Next())
+	arr := coll.ToArray()
+	if len(arr) == 0 {
+		return false
 	}
-	return changed
+	if len(arr) < len(this.data.data) {
+		for _, v := range arr {
+			heap.Push(&this.data, v)
+		}
+	} else {
+		// re-heapify is cheaper than pushing one by one
+		for _, v := range arr {
+			this.data.Push(v)
+		}
+		heap.Init(&this.data)
+	}
+	return true
 }
 
 func (this *PriorityQueue) RemoveAll(coll Collection) bool {
@@ -236,25 +610,30 @@ func (this *PriorityQueue) RetainAll(coll Collection) bool {
 }
 
 func (this *PriorityQueue) Clear() {
+	this.data.modCount++
 	this.data.data = make([]interface{}, 0)
+	if this.data.stable {
+		this.data.seqs = make([]uint64, 0)
+	}
+	if this.data.handles != nil {
+		for i := range this.data.handles {
+			this.data.invalidateHandle(i)
+		}
+		this.data.handles = make([]*PriorityHandle, 0)
+	}
 }
 
+// Equals follows the rules for queues, see Collection
 func (this *PriorityQueue) Equals(i interface{}) bool {
-	p, ok := i.(*PriorityQueue)
-	if ok {
-		return p == this
+	if p, ok := i.(*PriorityQueue); ok && p == this {
+		return true
 	}
-	return false
+	q, ok := i.(Queue)
+	return ok && QueueEquals(this, q)
 }
 
 func (this *PriorityQueue) HashCode() int {
-	hashCode := this.hashCode
-	if hashCode != 0 {
-		return hashCode
-	}
-	hashCode = int(uintptr(unsafe.Pointer(this)))
-	this.hashCode = hashCode
-	return hashCode
+	return QueueHashCode(this)
 }
 
 func (this *PriorityQueue) Offer(i interface{}) bool {
@@ -262,17 +641,46 @@ func (this *PriorityQueue) Offer(i interface{}) bool {
 	return true
 }
 
-func (this *PriorityQueue) RemoveHead() interface{} {
-	var i interface{} = nil
-	if !this.IsEmpty() {
-		i = heap.Pop(&this.data)
+// OfferWithHandle inserts i and returns a handle to update or remove it later
+func (this *PriorityQueue) OfferWithHandle(i interface{}) *PriorityHandle {
+	d := &this.data
+	if d.handles == nil {
+		d.handles = make([]*PriorityHandle, len(d.data), cap(d.data))
 	}
+	d.Push(i)
+	n := len(d.data) - 1
+	h := &PriorityHandle{queue: this, index: n}
+	d.handles[n] = h
+	heap.Fix(d, n)
+	return h
+}
+
+// TryAdd inserts i, return ErrNilElement if i is nil
+func (this *PriorityQueue) TryAdd(i interface{}) error {
 	if i == nil {
-		panic("queue is empty")
+		return ErrNilElement
+	}
+	this.Offer(i)
+	return nil
+}
+
+// RemoveHead panics if the queue is empty, see TryRemoveHead
+func (this *PriorityQueue) RemoveHead() interface{} {
+	i, err := this.TryRemoveHead()
+	if err != nil {
+		panic(err)
 	}
 	return i
 }
 
+// TryRemoveHead retrieves and removes the head, return ErrEmpty if the queue is empty
+func (this *PriorityQueue) TryRemoveHead() (interface{}, error) {
+	if this.IsEmpty() {
+		return nil, ErrEmpty
+	}
+	return heap.Pop(&this.data), nil
+}
+
 func (this *PriorityQueue) Poll() interface{} {
 	if this.IsEmpty() {
 		return nil
@@ -281,12 +689,21 @@ func (this *PriorityQueue) Poll() interface{} {
 	return i
 }
 
+// Element panics if the queue is empty, see TryElement
 func (this *PriorityQueue) Element() interface{} {
+	i, err := this.TryElement()
+	if err != nil {
+		panic(err)
+	}
+	return i
+}
+
+// TryElement retrieves the head, return ErrEmpty if the queue is empty
+func (this *PriorityQueue) TryElement() (interface{}, error) {
 	if this.IsEmpty() {
-		panic("queue is empty")
-	} else {
-		return this.data.data[0]
+		return nil, ErrEmpty
 	}
+	return this.data.data[0], nil
 }
 
 func (this *PriorityQueue) Peek() interface{} {
@@ -2,6 +2,7 @@ package guc
 
 import (
 	"container/list"
+	"math/rand"
 	"testing"
 )
 
@@ -258,8 +259,22 @@ func TestPriorityQueue_Equals(t *testing.T) {
 	if !p.Equals(p) {
 		t.Fatal("queue should be equals to itself")
 	}
-	if p.Equals(newPreparedPriorityQueue()) {
-		t.Fatal("queue should not be equals to a new one")
+	other := newPreparedPriorityQueue()
+	if !p.Equals(other) || !other.Equals(p) {
+		t.Fatal("queue should be equals to a queue with the same elements")
+	}
+	other.Poll()
+	other.Add(newSampleItem(2))
+	if !p.Equals(other) {
+		t.Fatal("queue equality should not depend on the order")
+	}
+	other.Add(newSampleItem(2))
+	if p.Equals(other) {
+		t.Fatal("queue should not be equals to a queue with more elements")
+	}
+	other.Remove(newSampleItem(6))
+	if p.Equals(other) {
+		t.Fatal("queue should not be equals to a queue with other multiplicities")
 	}
 	if p.Equals(struct{}{}) {
 		t.Fatal("queue should not be equals to another object of other type")
@@ -271,6 +286,19 @@ func TestPriorityQueue_HashCode(t *testing.T) {
 	if p.HashCode() != p.HashCode() {
 		t.Fatal("hashcode must same")
 	}
+	q := NewPriorityWithComparator(NaturalOrder())
+	q.Add(1)
+	q.Add(2)
+	r := NewPriorityWithComparator(Reversed(NaturalOrder()))
+	r.Add(2)
+	r.Add(1)
+	if !q.Equals(r) || q.HashCode() != r.HashCode() {
+		t.Fatal("equal queues must have the same hashcode")
+	}
+	r.Add(3)
+	if q.HashCode() == r.HashCode() {
+		t.Fatal("hashcode should depend on the elements")
+	}
 }
 
 func TestPriorityQueue_Offer(t *testing.T) {
@@ -375,6 +403,7 @@ func TestPriorityQueueIter_Remove(t *testing.T) {
 	p := newPreparedPriorityQueue()
 	iter := p.Iterator()
 	iter.HasNext()
+	iter.Next()
 	iter.Remove()
 	if p.Size() != 6 {
 		t.Fatal("queue size should be 6")
@@ -518,3 +547,315 @@ func TestPriorityQueue_RetainAll(t *testing.T) {
 		}
 	}
 }
+
+type sampleJob struct {
+	priority int
+	id       int
+}
+
+type sampleJobComparator struct {
+}
+
+func (sampleJobComparator) Compare(o1, o2 interface{}) int {
+	return o1.(*sampleJob).priority - o2.(*sampleJob).priority
+}
+
+func TestNewStablePriority(t *testing.T) {
+	p := NewStablePriority(sampleJobComparator{})
+	for i := 0; i < 100; i++ {
+		p.Add(&sampleJob{priority: i % 3, id: i})
+	}
+	lastPriority, lastId := -1, -1
+	for !p.IsEmpty() {
+		job := p.Poll().(*sampleJob)
+		if job.priority < lastPriority {
+			t.Fatal("priority order broken")
+		}
+		if job.priority == lastPriority && job.id < lastId {
+			t.Fatal("equal priority jobs should come out in insertion order")
+		}
+		lastPriority, lastId = job.priority, job.id
+	}
+}
+
+func TestStablePriorityQueue_Remove(t *testing.T) {
+	p := NewStablePriority(nil)
+	p.Add(newSampleItem(3))
+	p.Add(newSampleItem(1))
+	p.Add(newSampleItem(3))
+	p.Add(newSampleItem(2))
+	if !p.Remove(newSampleItem(1)) {
+		t.Fatal("remove result should be true")
+	}
+	iter := p.Iterator()
+	cnt := 0
+	for iter.HasNext() {
+		iter.Next()
+		cnt++
+	}
+	if cnt != 3 {
+		t.Fatal("iter count should be 3")
+	}
+	if len(p.data.seqs) != 3 {
+		t.Fatal("sequence numbers should follow the elements")
+	}
+	if p.Poll().(*sampleItem).Value != 2 {
+		t.Fatal("head should be value 2")
+	}
+	p.Clear()
+	if len(p.data.seqs) != 0 {
+		t.Fatal("sequence numbers should be cleared")
+	}
+}
+
+func TestPriorityQueue_OfferWithHandle(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	item := newSampleItem(50)
+	h := p.OfferWithHandle(item)
+	if p.data.data[h.Index()] != item {
+		t.Fatal("handle index should point to the element")
+	}
+	// decrease key
+	item.Value = 1
+	if !h.Update() {
+		t.Fatal("update result should be true")
+	}
+	if h.Index() != 0 {
+		t.Fatal("updated element should be the head")
+	}
+	p.Add(newSampleItem(0))
+	if p.data.data[h.Index()] != item {
+		t.Fatal("handle index should follow the element")
+	}
+	// increase key
+	item.Value = 100
+	h.Update()
+	for i := 0; i < 8; i++ {
+		if p.Poll() == item {
+			t.Fatal("updated element should be the last one")
+		}
+	}
+	if h.Value() != item {
+		t.Fatal("handle value should be the element")
+	}
+	if p.Poll() != item {
+		t.Fatal("updated element should be the last one")
+	}
+	if h.Index() != -1 || h.Update() || h.Remove() || h.Value() != nil {
+		t.Fatal("handle should be invalid after poll")
+	}
+}
+
+func TestPriorityHandle_Remove(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	handles := make([]*PriorityHandle, 0)
+	for i := 0; i < 10; i++ {
+		handles = append(handles, p.OfferWithHandle(newSampleItem(i*10)))
+	}
+	for _, h := range handles {
+		if !h.Remove() {
+			t.Fatal("remove result should be true")
+		}
+		for i, v := range p.data.handles {
+			if v != nil && v.Index() != i {
+				t.Fatal("handle index should match its position")
+			}
+		}
+	}
+	if p.Size() != 7 {
+		t.Fatal("queue size should be 7")
+	}
+	h := p.OfferWithHandle(newSampleItem(1))
+	p.Clear()
+	if h.Index() != -1 {
+		t.Fatal("handle should be invalid after clear")
+	}
+}
+
+func TestNewPriorityFromSlice(t *testing.T) {
+	arr := []interface{}{newSampleItem(6), newSampleItem(8), newSampleItem(3), newSampleItem(2)}
+	p := NewPriorityFromSlice(arr, nil)
+	if p.Size() != 4 {
+		t.Fatal("queue size should be 4")
+	}
+	p.Poll()
+	if arr[0].(*sampleItem).Value != 6 {
+		t.Fatal("source slice should not be modified")
+	}
+	if p.Poll().(*sampleItem).Value != 3 {
+		t.Fatal("should poll value 3")
+	}
+
+	c := NewPriorityFromCollection(newPreparedPriorityQueue(), nil)
+	if c.Size() != 7 {
+		t.Fatal("queue size should be 7")
+	}
+	if c.Peek().(*sampleItem).Value != 2 {
+		t.Fatal("head should be value 2")
+	}
+}
+
+func TestPriorityQueue_ToSortedArray(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	arr := p.ToSortedArray()
+	expected := []int{2, 3, 6, 6, 7, 8, 33}
+	if len(arr) != len(expected) {
+		t.Fatal("array size must be 7")
+	}
+	for i, v := range arr {
+		if v.(*sampleItem).Value != expected[i] {
+			t.Fatal("array should be sorted")
+		}
+	}
+	if p.Size() != 7 || p.Peek().(*sampleItem).Value != 2 {
+		t.Fatal("queue should not be modified")
+	}
+}
+
+func TestPriorityQueue_SortedIterator(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	iter := p.SortedIterator()
+	prev := -1
+	for iter.HasNext() {
+		v := iter.Next().(*sampleItem).Value
+		if v < prev {
+			t.Fatal("iterator should follow priority order")
+		}
+		if v == 33 {
+			iter.Remove()
+		}
+		prev = v
+	}
+	if p.Size() != 6 || p.Contains(newSampleItem(33)) {
+		t.Fatal("value 33 should be removed")
+	}
+
+	a, b := newSampleItem(1), newSampleItem(1)
+	p = NewPriority()
+	p.Add(a)
+	p.Add(b)
+	iter = p.SortedIterator()
+	for iter.HasNext() {
+		if iter.Next() == b {
+			iter.Remove()
+		}
+	}
+	if p.Size() != 1 || p.Peek() != a {
+		t.Fatal("iterator remove should remove the returned item, not an equal one")
+	}
+}
+
+func TestPriorityQueue_AddAll_Heapify(t *testing.T) {
+	p := NewPriority()
+	p.Add(newSampleItem(5))
+	h := p.OfferWithHandle(newSampleItem(4))
+	p.AddAll(newPreparedPriorityQueue())
+	if p.Size() != 9 {
+		t.Fatal("queue size should be 9")
+	}
+	if p.data.data[h.Index()].(*sampleItem).Value != 4 {
+		t.Fatal("handle index should follow the element")
+	}
+	arr := p.ToSortedArray()
+	for i := 0; i < len(arr); i++ {
+		if p.Poll() != arr[i] {
+			t.Fatal("poll order should match sorted array")
+		}
+	}
+}
+
+func TestPriorityQueueIter_FailFast(t *testing.T) {
+	p := newPreparedPriorityQueue()
+	iter := p.Iterator()
+	iter.Next()
+	p.Add(newSampleItem(1))
+	r := func() (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		iter.Next()
+		return
+	}()
+	if r != ErrConcurrentModification {
+		t.Fatal("iterator should fail after the queue is modified")
+	}
+
+	r = func() (err interface{}) {
+		defer func() {
+			err = recover()
+		}()
+		p.ForEach(func(i interface{}) {
+			p.Poll()
+		})
+		return
+	}()
+	if r != ErrConcurrentModification {
+		t.Fatal("for each should fail if consumer modifies the queue")
+	}
+}
+
+func TestPriorityQueueIter_RemoveVisitsAll(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		p := NewPriorityWithComparator(NaturalOrder())
+		n := 1 + rnd.Intn(64)
+		for i := 0; i < n; i++ {
+			p.Add(rnd.Intn(1000))
+		}
+		visited := 0
+		removed := 0
+		iter := p.Iterator()
+		for iter.HasNext() {
+			iter.Next()
+			visited++
+			// some removals sift the filling element up into the visited part
+			if rnd.Intn(2) == 0 {
+				iter.Remove()
+				removed++
+			}
+		}
+		if visited != n {
+			t.Fatal("each element should be visited exactly once, visited", visited, "of", n)
+		}
+		if p.Size() != n-removed {
+			t.Fatal("queue size should be", n-removed)
+		}
+		prev := -1
+		for !p.IsEmpty() {
+			v := p.Poll().(int)
+			if v < prev {
+				t.Fatal("heap order broken after iterator remove")
+			}
+			prev = v
+		}
+	}
+}
+
+func TestPriorityQueueIter_RemoveByIdentity(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for round := 0; round < 200; round++ {
+		p := NewPriority()
+		n := 1 + rnd.Intn(64)
+		for i := 0; i < n; i++ {
+			// many equal items, so only identity tells them apart
+			p.Add(newSampleItem(rnd.Intn(4)))
+		}
+		removed := make(map[*sampleItem]bool)
+		iter := p.Iterator()
+		for iter.HasNext() {
+			v := iter.Next().(*sampleItem)
+			if rnd.Intn(2) == 0 {
+				iter.Remove()
+				removed[v] = true
+			}
+		}
+		for _, v := range p.ToArray() {
+			if removed[v.(*sampleItem)] {
+				t.Fatal("iterator remove should remove the returned item, not an equal one")
+			}
+		}
+		if p.Size() != n-len(removed) {
+			t.Fatal("queue size should be", n-len(removed))
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+var _ Lock = new(ReentrantLock)
+
+// ReentrantLock is a mutual exclusion lock which its owner may acquire
+// again, it must release it as many times. Waiters sleep on their own
+// runtime semaphore so timed and context acquisitions can give up.
+//
+// A non fair lock lets an arriving owner barge in front of the queued ones,
+// a fair lock hands itself to the longest waiting owner in FIFO order.
+// TryLock barges in both modes. The zero value is a free non fair lock
+type ReentrantLock struct {
+	mu   sync.Mutex
+	fair bool
+	// Volatile, type is *Owner, written with mu held
+	owner unsafe.Pointer
+	// guarded by mu
+	holds int
+	queue waitQueue
+}
+
+func NewReentrantLock() *ReentrantLock {
+	return &ReentrantLock{}
+}
+
+func NewFairReentrantLock() *ReentrantLock {
+	return &ReentrantLock{fair: true}
+}
+
+func (this *ReentrantLock) getOwner() *Owner {
+	return (*Owner)(atomic.LoadPointer(&this.owner))
+}
+
+func (this *ReentrantLock) setOwner(owner *Owner, holds int) {
+	atomic.StorePointer(&this.owner, unsafe.Pointer(owner))
+	this.holds = holds
+}
+
+// tryAcquire must be called with mu held, a free lock is taken only if
+// barge is set or nobody is queued
+func (this *ReentrantLock) tryAcquire(owner *Owner, barge bool) bool {
+	cur := this.getOwner()
+	if cur == owner {
+		this.holds++
+		return true
+	}
+	if cur == nil && (barge || this.queue.size == 0) {
+		this.setOwner(owner, 1)
+		return true
+	}
+	return false
+}
+
+func (this *ReentrantLock) Lock(owner *Owner) {
+	this.acquire(nil, owner, time.Time{})
+}
+
+func (this *ReentrantLock) LockContext(ctx context.Context, owner *Owner) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !this.acquire(ctx, owner, time.Time{}) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (this *ReentrantLock) TryLock(owner *Owner) bool {
+	checkOwner(owner)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.tryAcquire(owner, true)
+}
+
+func (this *ReentrantLock) TryLockWithTimeout(owner *Owner, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return this.TryLock(owner)
+	}
+	return this.acquire(nil, owner, time.Now().Add(timeout))
+}
+
+// acquire blocks until owner holds the lock, the deadline passes or ctx is
+// done, return true if the lock is held
+func (this *ReentrantLock) acquire(ctx context.Context, owner *Owner, deadline time.Time) bool {
+	checkOwner(owner)
+	if !this.fair {
+		// the lock is usually held shortly, spin a little before queueing
+		for i := 0; SyncRuntimeCanSpin(i); i++ {
+			if this.getOwner() == nil && this.TryLock(owner) {
+				return true
+			}
+			SyncRuntimeDoSpin()
+		}
+	}
+	this.mu.Lock()
+	if this.tryAcquire(owner, !this.fair) {
+		this.mu.Unlock()
+		return true
+	}
+	w := &waiter{owner: owner}
+	this.queue.pushBack(w)
+	for {
+		this.mu.Unlock()
+		if !w.await(ctx, deadline) {
+			this.mu.Lock()
+			this.queue.remove(w)
+			if this.fair && this.getOwner() == nil {
+				// a free fair lock with waiters behind w must not stall
+				this.release()
+			}
+			this.mu.Unlock()
+			return false
+		}
+		if this.fair {
+			// the lock was handed over by release
+			return true
+		}
+		this.mu.Lock()
+		if this.tryAcquire(owner, true) {
+			this.mu.Unlock()
+			return true
+		}
+		// lost to a barging owner, wait again at the head
+		w = &waiter{owner: owner}
+		this.queue.pushFront(w)
+	}
+}
+
+func (this *ReentrantLock) Unlock(owner *Owner) {
+	this.mu.Lock()
+	if owner == nil || this.getOwner() != owner {
+		this.mu.Unlock()
+		panic(ErrNotOwner)
+	}
+	this.holds--
+	if this.holds == 0 {
+		this.setOwner(nil, 0)
+		this.release()
+	}
+	this.mu.Unlock()
+}
+
+// release wakes the first waiter which isn't cancelled, a fair lock is
+// handed over to it. must be called with mu held and the lock free
+func (this *ReentrantLock) release() {
+	for w := this.queue.front(); w != nil; w = this.queue.front() {
+		this.queue.remove(w)
+		if this.fair {
+			this.setOwner(w.owner, 1)
+		}
+		if w.signal() {
+			return
+		}
+		if this.fair {
+			this.setOwner(nil, 0)
+		}
+	}
+}
+
+// NewCondition creates a condition bound to this lock and owner. Its await
+// methods must be called while owner holds the lock, otherwise they panic
+// with ErrNotOwner. they release all its holds while waiting and restore
+// them on return
+func (this *ReentrantLock) NewCondition(owner *Owner) *Condition {
+	checkOwner(owner)
+	return &Condition{lock: reentrantCondition{l: this, owner: owner}}
+}
+
+type reentrantCondition struct {
+	l     *ReentrantLock
+	owner *Owner
+}
+
+func (this reentrantCondition) releaseAll() func() {
+	l := this.l
+	l.mu.Lock()
+	if l.getOwner() != this.owner {
+		l.mu.Unlock()
+		panic(ErrNotOwner)
+	}
+	holds := l.holds
+	l.setOwner(nil, 0)
+	l.release()
+	l.mu.Unlock()
+	return func() {
+		l.acquire(nil, this.owner, time.Time{})
+		l.mu.Lock()
+		l.holds = holds
+		l.mu.Unlock()
+	}
+}
+
+// IsHeldByCurrentOwner returns true if owner holds the lock
+func (this *ReentrantLock) IsHeldByCurrentOwner(owner *Owner) bool {
+	return owner != nil && this.getOwner() == owner
+}
+
+// GetHoldCount returns how many times owner holds the lock, 0 if it
+// doesn't hold it
+func (this *ReentrantLock) GetHoldCount(owner *Owner) int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if owner == nil || this.getOwner() != owner {
+		return 0
+	}
+	return this.holds
+}
+
+// GetQueueLength returns an estimate of the number of waiting owners
+func (this *ReentrantLock) GetQueueLength() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.queue.size
+}
+
+func (this *ReentrantLock) HasQueuedWaiters() bool {
+	return this.GetQueueLength() > 0
+}
+
+func (this *ReentrantLock) IsLocked() bool {
+	return this.getOwner() != nil
+}
+
+func (this *ReentrantLock) IsFair() bool {
+	return this.fair
+}
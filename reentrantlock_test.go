@@ -0,0 +1,139 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReentrantLock(t *testing.T) {
+	l := NewReentrantLock()
+	o1, o2 := NewOwner(), NewOwner()
+	l.Lock(o1)
+	l.Lock(o1)
+	if !l.TryLock(o1) || l.GetHoldCount(o1) != 3 || !l.IsHeldByCurrentOwner(o1) {
+		t.Fatal("owner should hold the lock 3 times")
+	}
+	if l.TryLock(o2) || l.IsHeldByCurrentOwner(o2) || l.GetHoldCount(o2) != 0 {
+		t.Fatal("another owner should not get the lock")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == ErrNotOwner
+		}()
+		l.Unlock(o2)
+		return
+	}()
+	if !r {
+		t.Fatal("unlock by another owner should panic with ErrNotOwner")
+	}
+	l.Unlock(o1)
+	l.Unlock(o1)
+	if !l.IsLocked() {
+		t.Fatal("lock should still be held once")
+	}
+	l.Unlock(o1)
+	if l.IsLocked() || !l.TryLock(o2) {
+		t.Fatal("lock should be free")
+	}
+	l.Unlock(o2)
+}
+
+func TestReentrantLock_Timeout(t *testing.T) {
+	l := NewReentrantLock()
+	o1, o2 := NewOwner(), NewOwner()
+	l.Lock(o1)
+	start := time.Now()
+	if l.TryLockWithTimeout(o2, 20*time.Millisecond) {
+		t.Fatal("try lock should time out")
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatal("try lock returned before the timeout")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.LockContext(ctx, o2)
+	}()
+	for l.GetQueueLength() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatal("lock context should return context.Canceled")
+	}
+	if l.GetQueueLength() != 0 {
+		t.Fatal("cancelled waiter should leave the queue")
+	}
+	go func() {
+		done <- l.LockContext(context.Background(), o2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	l.Unlock(o1)
+	if err := <-done; err != nil || !l.IsHeldByCurrentOwner(o2) {
+		t.Fatal("waiter should get the lock once released")
+	}
+	l.Unlock(o2)
+}
+
+func TestReentrantLock_Fair(t *testing.T) {
+	l := NewFairReentrantLock()
+	holder := NewOwner()
+	l.Lock(holder)
+	n := 5
+	order := make(chan int, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			o := NewOwner()
+			l.Lock(o)
+			order <- i
+			l.Unlock(o)
+		}(i)
+		for l.GetQueueLength() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	// a cancelled waiter in the middle doesn't break the order
+	if l.TryLockWithTimeout(NewOwner(), 5*time.Millisecond) {
+		t.Fatal("try lock should time out")
+	}
+	l.Unlock(holder)
+	for i := 0; i < n; i++ {
+		if v := <-order; v != i {
+			t.Fatalf("fair lock granted %d, expected %d", v, i)
+		}
+	}
+}
+
+func TestReentrantLock_Concurrent(t *testing.T) {
+	for _, l := range []*ReentrantLock{NewReentrantLock(), NewFairReentrantLock()} {
+		var wg sync.WaitGroup
+		cnt := 0
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				o := NewOwner()
+				for i := 0; i < 1000; i++ {
+					if i%100 == 0 {
+						if l.TryLockWithTimeout(o, time.Microsecond) {
+							cnt++
+							l.Unlock(o)
+						}
+						continue
+					}
+					l.Lock(o)
+					l.Lock(o)
+					cnt++
+					l.Unlock(o)
+					l.Unlock(o)
+				}
+			}()
+		}
+		wg.Wait()
+		if cnt < 8*990 || cnt > 8*1000 || l.IsLocked() {
+			t.Fatalf("count %d out of range, or lock still held", cnt)
+		}
+	}
+}
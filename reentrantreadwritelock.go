@@ -0,0 +1,356 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ReadWriteLock is a pair of locks, the read lock may be held by many
+// owners at once as long as no owner holds the write lock
+type ReadWriteLock interface {
+	ReadLock() Lock
+	WriteLock() Lock
+}
+
+var _ ReadWriteLock = new(ReentrantReadWriteLock)
+
+// ReentrantReadWriteLock is a ReadWriteLock whose both locks are reentrant.
+// The write lock holder may acquire the read lock, then release the write
+// lock to downgrade. A read holder can't upgrade: TryWriteLock fails and
+// WriteLock blocks forever.
+//
+// In non fair mode writers barge in and new readers only wait behind a
+// queued writer. In fair mode both locks are handed over in FIFO order,
+// a run of queued readers is admitted together. TryReadLock and TryWriteLock
+// without timeout barge in both modes. The zero value is a free non fair lock
+type ReentrantReadWriteLock struct {
+	mu   sync.Mutex
+	fair bool
+	// guarded by mu
+	writer     *Owner
+	writeHolds int
+	readers    map[*Owner]int
+	readCount  int
+	queue      waitQueue
+}
+
+func NewReentrantReadWriteLock() *ReentrantReadWriteLock {
+	return &ReentrantReadWriteLock{}
+}
+
+func NewFairReentrantReadWriteLock() *ReentrantReadWriteLock {
+	return &ReentrantReadWriteLock{fair: true}
+}
+
+func (this *ReentrantReadWriteLock) ReadLock() Lock {
+	return (*rwReadLock)(this)
+}
+
+func (this *ReentrantReadWriteLock) WriteLock() Lock {
+	return (*rwWriteLock)(this)
+}
+
+// mayBarge returns true if an arriving owner doesn't have to queue
+// behind the waiters
+func (this *ReentrantReadWriteLock) mayBarge(shared bool) bool {
+	if this.fair {
+		return this.queue.size == 0
+	}
+	if shared {
+		f := this.queue.front()
+		return f == nil || f.shared
+	}
+	return true
+}
+
+// canAcquire must be called with mu held, first is set for owners
+// which don't have to respect the queue
+func (this *ReentrantReadWriteLock) canAcquire(owner *Owner, shared, first bool) bool {
+	if this.writer == owner {
+		return true
+	}
+	if this.writer != nil {
+		return false
+	}
+	if shared {
+		// reentrant reads never wait, a queued writer would deadlock them
+		return this.readers[owner] > 0 || first || this.mayBarge(true)
+	}
+	return this.readCount == 0 && (first || this.mayBarge(false))
+}
+
+func (this *ReentrantReadWriteLock) apply(owner *Owner, shared bool) {
+	if shared {
+		if this.readers == nil {
+			this.readers = make(map[*Owner]int)
+		}
+		this.readers[owner]++
+		this.readCount++
+	} else {
+		this.writer = owner
+		this.writeHolds++
+	}
+}
+
+func (this *ReentrantReadWriteLock) undo(owner *Owner, shared bool) {
+	if shared {
+		if this.readers[owner]--; this.readers[owner] == 0 {
+			delete(this.readers, owner)
+		}
+		this.readCount--
+	} else {
+		if this.writeHolds--; this.writeHolds == 0 {
+			this.writer = nil
+		}
+	}
+}
+
+func (this *ReentrantReadWriteLock) tryAcquire(owner *Owner, shared bool) bool {
+	checkOwner(owner)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.canAcquire(owner, shared, true) {
+		this.apply(owner, shared)
+		return true
+	}
+	return false
+}
+
+// acquire blocks until owner holds the lock, the deadline passes or ctx is
+// done, return true if the lock is held
+func (this *ReentrantReadWriteLock) acquire(ctx context.Context, owner *Owner, shared bool, deadline time.Time) bool {
+	checkOwner(owner)
+	this.mu.Lock()
+	if this.canAcquire(owner, shared, false) {
+		this.apply(owner, shared)
+		this.mu.Unlock()
+		return true
+	}
+	w := &waiter{owner: owner, shared: shared}
+	this.queue.pushBack(w)
+	for {
+		this.mu.Unlock()
+		if !w.await(ctx, deadline) {
+			this.mu.Lock()
+			this.queue.remove(w)
+			// w may have held back the waiters behind it
+			if f := this.queue.front(); f != nil && this.canAcquire(f.owner, f.shared, true) {
+				this.release()
+			}
+			this.mu.Unlock()
+			return false
+		}
+		if this.fair {
+			// the lock was handed over by release
+			return true
+		}
+		this.mu.Lock()
+		if this.canAcquire(owner, shared, true) {
+			this.apply(owner, shared)
+			this.mu.Unlock()
+			return true
+		}
+		// lost to a barging owner, wait again at the head
+		w = &waiter{owner: owner, shared: shared}
+		this.queue.pushFront(w)
+	}
+}
+
+// release wakes the waiters which may acquire the lock now: a writer or a
+// run of readers. a fair lock is handed over to them, must be called with
+// mu held
+func (this *ReentrantReadWriteLock) release() {
+	wokeReader := false
+	for w := this.queue.front(); w != nil; w = this.queue.front() {
+		if this.fair {
+			if !this.canAcquire(w.owner, w.shared, true) {
+				return
+			}
+			this.queue.remove(w)
+			this.apply(w.owner, w.shared)
+			if !w.signal() {
+				this.undo(w.owner, w.shared)
+			}
+			continue
+		}
+		if this.writer != nil || (!w.shared && (this.readCount > 0 || wokeReader)) {
+			return
+		}
+		this.queue.remove(w)
+		if !w.signal() {
+			continue
+		}
+		if !w.shared {
+			return
+		}
+		// wake the following readers too, they retry together
+		wokeReader = true
+	}
+}
+
+func (this *ReentrantReadWriteLock) unlock(owner *Owner, shared bool) {
+	this.mu.Lock()
+	held := owner != nil && this.writer == owner
+	if shared {
+		held = owner != nil && this.readers[owner] > 0
+	}
+	if !held {
+		this.mu.Unlock()
+		panic(ErrNotOwner)
+	}
+	this.undo(owner, shared)
+	if this.writer == nil {
+		this.release()
+	}
+	this.mu.Unlock()
+}
+
+func (this *ReentrantReadWriteLock) LockRead(owner *Owner) {
+	this.acquire(nil, owner, true, time.Time{})
+}
+
+func (this *ReentrantReadWriteLock) LockReadContext(ctx context.Context, owner *Owner) error {
+	return this.lockContext(ctx, owner, true)
+}
+
+func (this *ReentrantReadWriteLock) TryReadLock(owner *Owner) bool {
+	return this.tryAcquire(owner, true)
+}
+
+func (this *ReentrantReadWriteLock) TryReadLockWithTimeout(owner *Owner, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return this.tryAcquire(owner, true)
+	}
+	return this.acquire(nil, owner, true, time.Now().Add(timeout))
+}
+
+func (this *ReentrantReadWriteLock) UnlockRead(owner *Owner) {
+	this.unlock(owner, true)
+}
+
+func (this *ReentrantReadWriteLock) LockWrite(owner *Owner) {
+	this.acquire(nil, owner, false, time.Time{})
+}
+
+func (this *ReentrantReadWriteLock) LockWriteContext(ctx context.Context, owner *Owner) error {
+	return this.lockContext(ctx, owner, false)
+}
+
+func (this *ReentrantReadWriteLock) TryWriteLock(owner *Owner) bool {
+	return this.tryAcquire(owner, false)
+}
+
+func (this *ReentrantReadWriteLock) TryWriteLockWithTimeout(owner *Owner, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return this.tryAcquire(owner, false)
+	}
+	return this.acquire(nil, owner, false, time.Now().Add(timeout))
+}
+
+func (this *ReentrantReadWriteLock) UnlockWrite(owner *Owner) {
+	this.unlock(owner, false)
+}
+
+func (this *ReentrantReadWriteLock) lockContext(ctx context.Context, owner *Owner, shared bool) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !this.acquire(ctx, owner, shared, time.Time{}) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// GetReadLockCount returns the number of read holds of all owners
+func (this *ReentrantReadWriteLock) GetReadLockCount() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.readCount
+}
+
+// GetReadHoldCount returns the number of read holds of owner
+func (this *ReentrantReadWriteLock) GetReadHoldCount(owner *Owner) int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.readers[owner]
+}
+
+func (this *ReentrantReadWriteLock) IsWriteLocked() bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.writer != nil
+}
+
+func (this *ReentrantReadWriteLock) IsWriteLockedByOwner(owner *Owner) bool {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return owner != nil && this.writer == owner
+}
+
+// GetWriteHoldCount returns the number of write holds of owner
+func (this *ReentrantReadWriteLock) GetWriteHoldCount(owner *Owner) int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if owner == nil || this.writer != owner {
+		return 0
+	}
+	return this.writeHolds
+}
+
+// GetQueueLength returns an estimate of the number of waiting owners
+func (this *ReentrantReadWriteLock) GetQueueLength() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.queue.size
+}
+
+func (this *ReentrantReadWriteLock) IsFair() bool {
+	return this.fair
+}
+
+// rwReadLock is the read lock view of a ReentrantReadWriteLock
+type rwReadLock ReentrantReadWriteLock
+
+func (this *rwReadLock) Lock(owner *Owner) {
+	(*ReentrantReadWriteLock)(this).LockRead(owner)
+}
+
+func (this *rwReadLock) LockContext(ctx context.Context, owner *Owner) error {
+	return (*ReentrantReadWriteLock)(this).LockReadContext(ctx, owner)
+}
+
+func (this *rwReadLock) TryLock(owner *Owner) bool {
+	return (*ReentrantReadWriteLock)(this).TryReadLock(owner)
+}
+
+func (this *rwReadLock) TryLockWithTimeout(owner *Owner, timeout time.Duration) bool {
+	return (*ReentrantReadWriteLock)(this).TryReadLockWithTimeout(owner, timeout)
+}
+
+func (this *rwReadLock) Unlock(owner *Owner) {
+	(*ReentrantReadWriteLock)(this).UnlockRead(owner)
+}
+
+// rwWriteLock is the write lock view of a ReentrantReadWriteLock
+type rwWriteLock ReentrantReadWriteLock
+
+func (this *rwWriteLock) Lock(owner *Owner) {
+	(*ReentrantReadWriteLock)(this).LockWrite(owner)
+}
+
+func (this *rwWriteLock) LockContext(ctx context.Context, owner *Owner) error {
+	return (*ReentrantReadWriteLock)(this).LockWriteContext(ctx, owner)
+}
+
+func (this *rwWriteLock) TryLock(owner *Owner) bool {
+	return (*ReentrantReadWriteLock)(this).TryWriteLock(owner)
+}
+
+func (this *rwWriteLock) TryLockWithTimeout(owner *Owner, timeout time.Duration) bool {
+	return (*ReentrantReadWriteLock)(this).TryWriteLockWithTimeout(owner, timeout)
+}
+
+func (this *rwWriteLock) Unlock(owner *Owner) {
+	(*ReentrantReadWriteLock)(this).UnlockWrite(owner)
+}
@@ -0,0 +1,141 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestReentrantReadWriteLock(t *testing.T) {
+	l := NewReentrantReadWriteLock()
+	o1, o2 := NewOwner(), NewOwner()
+	l.LockRead(o1)
+	l.LockRead(o1)
+	if !l.TryReadLock(o2) || l.GetReadLockCount() != 3 || l.GetReadHoldCount(o1) != 2 {
+		t.Fatal("readers should share the lock")
+	}
+	if l.TryWriteLock(o1) || l.TryWriteLockWithTimeout(o2, time.Millisecond) {
+		t.Fatal("write lock should wait for the readers")
+	}
+	l.UnlockRead(o1)
+	l.UnlockRead(o1)
+	l.UnlockRead(o2)
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == ErrNotOwner
+		}()
+		l.UnlockRead(o2)
+		return
+	}()
+	if !r {
+		t.Fatal("unlock of a read lock not held should panic with ErrNotOwner")
+	}
+	l.LockWrite(o1)
+	if !l.TryWriteLock(o1) || l.GetWriteHoldCount(o1) != 2 || !l.IsWriteLocked() || !l.IsWriteLockedByOwner(o1) {
+		t.Fatal("writer should hold the lock twice")
+	}
+	if l.TryReadLock(o2) || l.TryWriteLock(o2) {
+		t.Fatal("the write lock should exclude other owners")
+	}
+	l.UnlockWrite(o1)
+	l.UnlockWrite(o1)
+	if l.IsWriteLocked() {
+		t.Fatal("lock should be free")
+	}
+}
+
+func TestReentrantReadWriteLock_Downgrade(t *testing.T) {
+	l := NewReentrantReadWriteLock()
+	o1, o2 := NewOwner(), NewOwner()
+	l.WriteLock().Lock(o1)
+	l.ReadLock().Lock(o1)
+	l.WriteLock().Unlock(o1)
+	if l.IsWriteLocked() || l.GetReadHoldCount(o1) != 1 {
+		t.Fatal("writer should have downgraded to a reader")
+	}
+	if !l.ReadLock().TryLock(o2) || l.WriteLock().TryLock(o1) {
+		t.Fatal("downgraded lock should admit readers only")
+	}
+	l.ReadLock().Unlock(o2)
+	done := make(chan error)
+	go func() {
+		done <- l.WriteLock().LockContext(context.Background(), o2)
+	}()
+	for l.GetQueueLength() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	l.ReadLock().Unlock(o1)
+	if err := <-done; err != nil || !l.IsWriteLockedByOwner(o2) {
+		t.Fatal("writer should get the lock once the reader is gone")
+	}
+	l.WriteLock().Unlock(o2)
+}
+
+func TestReentrantReadWriteLock_Fair(t *testing.T) {
+	l := NewFairReentrantReadWriteLock()
+	holder := NewOwner()
+	l.LockWrite(holder)
+	order := make(chan string, 4)
+	start := func(name string, shared bool) {
+		go func() {
+			o := NewOwner()
+			if shared {
+				l.LockRead(o)
+				order <- name
+				time.Sleep(10 * time.Millisecond)
+				l.UnlockRead(o)
+			} else {
+				l.LockWrite(o)
+				order <- name
+				l.UnlockWrite(o)
+			}
+		}()
+	}
+	for i, name := range []string{"r1", "r2", "w", "r3"} {
+		start(name, name[0] == 'r')
+		for l.GetQueueLength() != i+1 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if l.TryReadLockWithTimeout(NewOwner(), time.Millisecond) {
+		t.Fatal("fair reader should queue behind the others")
+	}
+	l.UnlockWrite(holder)
+	got := []string{<-order, <-order, <-order, <-order}
+	if !(got[0][0] == 'r' && got[1][0] == 'r' && got[2] == "w" && got[3] == "r3") {
+		t.Fatalf("fair lock order error %v", got)
+	}
+}
+
+func TestReentrantReadWriteLock_Concurrent(t *testing.T) {
+	for _, l := range []*ReentrantReadWriteLock{NewReentrantReadWriteLock(), NewFairReentrantReadWriteLock()} {
+		var wg sync.WaitGroup
+		data := [2]int{}
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				o := NewOwner()
+				for i := 0; i < 500; i++ {
+					if g%2 == 0 {
+						l.LockWrite(o)
+						data[0]++
+						data[1]++
+						l.UnlockWrite(o)
+					} else {
+						l.LockRead(o)
+						if data[0] != data[1] {
+							t.Error("reader saw a partial write")
+						}
+						l.UnlockRead(o)
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+		if data[0] != 4*500 || l.IsWriteLocked() || l.GetReadLockCount() != 0 {
+			t.Fatal("concurrent read write error")
+		}
+	}
+}
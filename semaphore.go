@@ -0,0 +1,165 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Semaphore is a counting semaphore, Acquire takes permits and Release
+// gives them back, any goroutine may release. Queued acquisitions are
+// handed their permits by Release.
+//
+// A non fair semaphore lets arriving goroutines barge in front of the
+// queued ones and serves any queued request which fits in the released
+// permits. A fair semaphore serves requests in FIFO order, so a large
+// request can't be starved by small ones. TryAcquire barges in both modes
+type Semaphore struct {
+	mu   sync.Mutex
+	fair bool
+	// guarded by mu
+	permits int
+	queue   waitQueue
+}
+
+func NewSemaphore(permits int) *Semaphore {
+	return &Semaphore{permits: permits}
+}
+
+func NewFairSemaphore(permits int) *Semaphore {
+	return &Semaphore{permits: permits, fair: true}
+}
+
+func checkPermits(n int) {
+	if n < 0 {
+		panic(ErrIllegalArgument)
+	}
+}
+
+// Acquire blocks until n permits are available and takes them
+func (this *Semaphore) Acquire(n int) {
+	this.acquire(nil, n, time.Time{})
+}
+
+// AcquireContext is like Acquire, it returns ctx.Err() if ctx is done first
+func (this *Semaphore) AcquireContext(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !this.acquire(ctx, n, time.Time{}) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// TryAcquire takes n permits only if they are available at the time of
+// invocation
+func (this *Semaphore) TryAcquire(n int) bool {
+	checkPermits(n)
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	if this.permits >= n {
+		this.permits -= n
+		return true
+	}
+	return false
+}
+
+// TryAcquireWithTimeout waits at most timeout for n permits
+func (this *Semaphore) TryAcquireWithTimeout(n int, timeout time.Duration) bool {
+	if timeout <= 0 {
+		return this.TryAcquire(n)
+	}
+	return this.acquire(nil, n, time.Now().Add(timeout))
+}
+
+// acquire blocks until n permits are taken, the deadline passes or ctx is
+// done, return true if the permits are taken
+func (this *Semaphore) acquire(ctx context.Context, n int, deadline time.Time) bool {
+	checkPermits(n)
+	this.mu.Lock()
+	if this.permits >= n && (!this.fair || this.queue.size == 0) {
+		this.permits -= n
+		this.mu.Unlock()
+		return true
+	}
+	w := &waiter{n: n}
+	this.queue.pushBack(w)
+	this.mu.Unlock()
+	if w.await(ctx, deadline) {
+		// the permits were handed over by release
+		return true
+	}
+	this.mu.Lock()
+	this.queue.remove(w)
+	// w may have held back the waiters behind it
+	this.release()
+	this.mu.Unlock()
+	return false
+}
+
+// Release gives back n permits, n may exceed the permits acquired
+func (this *Semaphore) Release(n int) {
+	checkPermits(n)
+	this.mu.Lock()
+	this.permits += n
+	this.release()
+	this.mu.Unlock()
+}
+
+// release hands the permits over to the queued requests which fit,
+// must be called with mu held
+func (this *Semaphore) release() {
+	for w := this.queue.front(); w != nil; {
+		next := w.next
+		if this.permits >= w.n {
+			this.queue.remove(w)
+			this.permits -= w.n
+			if !w.signal() {
+				this.permits += w.n
+			}
+		} else if this.fair {
+			return
+		}
+		w = next
+	}
+}
+
+// AvailablePermits returns the current number of permits
+func (this *Semaphore) AvailablePermits() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.permits
+}
+
+// DrainPermits takes all the available permits and returns their number
+func (this *Semaphore) DrainPermits() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	n := this.permits
+	if n > 0 {
+		this.permits = 0
+		return n
+	}
+	return 0
+}
+
+// ReducePermits removes n permits without waiting, the count may go
+// negative
+func (this *Semaphore) ReducePermits(n int) {
+	checkPermits(n)
+	this.mu.Lock()
+	this.permits -= n
+	this.mu.Unlock()
+}
+
+// GetQueueLength returns an estimate of the number of waiting goroutines
+func (this *Semaphore) GetQueueLength() int {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+	return this.queue.size
+}
+
+func (this *Semaphore) IsFair() bool {
+	return this.fair
+}
@@ -0,0 +1,118 @@
+package guc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	s := NewSemaphore(3)
+	s.Acquire(2)
+	if !s.TryAcquire(1) || s.TryAcquire(1) || s.AvailablePermits() != 0 {
+		t.Fatal("semaphore should have given 3 permits")
+	}
+	if s.TryAcquireWithTimeout(1, 10*time.Millisecond) {
+		t.Fatal("try acquire should time out")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if s.AcquireContext(ctx, 1) != context.DeadlineExceeded || s.GetQueueLength() != 0 {
+		t.Fatal("acquire context should return context.DeadlineExceeded")
+	}
+	done := make(chan bool)
+	go func() {
+		done <- s.TryAcquireWithTimeout(2, time.Minute)
+	}()
+	s.Release(1)
+	for s.GetQueueLength() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Release(2)
+	if !<-done || s.AvailablePermits() != 1 {
+		t.Fatal("waiter should get the 2 released permits")
+	}
+	s.Release(4)
+	if s.DrainPermits() != 5 || s.AvailablePermits() != 0 {
+		t.Fatal("drain should take all the permits")
+	}
+	s.ReducePermits(2)
+	if s.AvailablePermits() != -2 || s.DrainPermits() != 0 {
+		t.Fatal("reduce permits error")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == ErrIllegalArgument
+		}()
+		s.Acquire(-1)
+		return
+	}()
+	if !r {
+		t.Fatal("negative permits should panic with ErrIllegalArgument")
+	}
+}
+
+func TestSemaphore_Fair(t *testing.T) {
+	for _, fair := range []bool{false, true} {
+		s := NewSemaphore(0)
+		if fair {
+			s = NewFairSemaphore(0)
+		}
+		got := make(chan int, 2)
+		for _, n := range []int{3, 1} {
+			go func(n int) {
+				s.Acquire(n)
+				got <- n
+			}(n)
+			for s.GetQueueLength() == 0 || (n == 1 && s.GetQueueLength() != 2) {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		s.Release(1)
+		select {
+		case n := <-got:
+			if fair || n != 1 {
+				t.Fatalf("fair %v semaphore served %d", fair, n)
+			}
+		case <-time.After(20 * time.Millisecond):
+			if !fair {
+				t.Fatal("non fair semaphore should serve the small request")
+			}
+		}
+		s.Release(3)
+		if fair && <-got+<-got != 4 {
+			t.Fatal("fair semaphore should serve both requests")
+		}
+		if !fair && <-got != 3 {
+			t.Fatal("large request should be served")
+		}
+	}
+}
+
+func TestSemaphore_Concurrent(t *testing.T) {
+	for _, s := range []*Semaphore{NewSemaphore(3), NewFairSemaphore(3)} {
+		var inside, max int32
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					s.Acquire(1)
+					n := atomic.AddInt32(&inside, 1)
+					if n > atomic.LoadInt32(&max) {
+						atomic.StoreInt32(&max, n)
+					}
+					atomic.AddInt32(&inside, -1)
+					s.Release(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if max > 3 || s.AvailablePermits() != 3 {
+			t.Fatalf("%d goroutines inside, expected at most 3", max)
+		}
+	}
+}
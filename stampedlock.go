@@ -0,0 +1,344 @@
+package guc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// state of a StampedLock: the low bits count the readers, the next bit is
+// set while write locked, the high bits are a version bumped by every
+// write unlock
+const (
+	slLGReaders = 16
+	slRUnit     = uint64(1)
+	slWBit      = uint64(1) << slLGReaders
+	slRBits     = slWBit - 1
+	slRFull     = slRBits - 1
+	slABits     = slRBits | slWBit
+	slSBits     = ^slRBits
+	// the lowest non zero version, so that no stamp is 0
+	slOrigin = slWBit << 1
+)
+
+// StampedLock is a capability based lock with three modes: writing, reading
+// and optimistic reading. Every acquisition returns a stamp which releases
+// or converts the mode, 0 means failure. The lock is not reentrant.
+//
+// An optimistic read writes no shared memory: take a stamp with
+// TryOptimisticRead, copy the fields and use the copies only if Validate
+// returns true. The fields must be read with atomics to be free of data
+// races in the Go memory model.
+//
+// Waiters spin a little, then park on a runtime semaphore. More than 65534
+// concurrent readers wait for a reader to leave. The zero value is unlocked
+type StampedLock struct {
+	// Volatile, stored minus slOrigin so the zero value is valid
+	state uint64
+	// Volatile, number of queued waiters
+	waiting int32
+	mu      sync.Mutex
+	// guarded by mu
+	queue waitQueue
+}
+
+func NewStampedLock() *StampedLock {
+	return &StampedLock{}
+}
+
+func (this *StampedLock) load() uint64 {
+	return atomic.LoadUint64(&this.state) + slOrigin
+}
+
+func (this *StampedLock) store(s uint64) {
+	atomic.StoreUint64(&this.state, s-slOrigin)
+}
+
+func (this *StampedLock) cas(old, new uint64) bool {
+	return atomic.CompareAndSwapUint64(&this.state, old-slOrigin, new-slOrigin)
+}
+
+// nextVersion returns the state after a write unlock of write locked state s
+func nextVersion(s uint64) uint64 {
+	if s += slWBit; s == 0 {
+		return slOrigin
+	}
+	return s
+}
+
+// TryWriteLock returns a write stamp if the lock is free, else 0
+func (this *StampedLock) TryWriteLock() uint64 {
+	s := this.load()
+	if s&slABits == 0 && this.cas(s, s+slWBit) {
+		return s + slWBit
+	}
+	return 0
+}
+
+// TryReadLock returns a read stamp if the lock isn't write locked, else 0
+func (this *StampedLock) TryReadLock() uint64 {
+	for {
+		s := this.load()
+		m := s & slABits
+		if m >= slRFull {
+			return 0
+		}
+		if this.cas(s, s+slRUnit) {
+			return s + slRUnit
+		}
+	}
+}
+
+// WriteLock blocks until the lock is exclusively held, return a write stamp
+func (this *StampedLock) WriteLock() uint64 {
+	return this.acquire(false, this.TryWriteLock)
+}
+
+// ReadLock blocks until the lock isn't write locked, return a read stamp
+func (this *StampedLock) ReadLock() uint64 {
+	return this.acquire(true, this.TryReadLock)
+}
+
+func (this *StampedLock) acquire(shared bool, try func() uint64) uint64 {
+	for i := 0; ; i++ {
+		if stamp := try(); stamp != 0 {
+			return stamp
+		}
+		if SyncRuntimeCanSpin(i) {
+			SyncRuntimeDoSpin()
+			continue
+		}
+		if stamp := this.park(shared, try); stamp != 0 {
+			return stamp
+		}
+		i = 0
+	}
+}
+
+// park queues the caller and sleeps until woken by an unlock, return a
+// stamp if try succeeds once queued, 0 after a wakeup
+func (this *StampedLock) park(shared bool, try func() uint64) uint64 {
+	w := &waiter{shared: shared}
+	this.mu.Lock()
+	this.queue.pushBack(w)
+	atomic.AddInt32(&this.waiting, 1)
+	this.mu.Unlock()
+	// an unlock before waiting was counted didn't see us, check again
+	if stamp := try(); stamp != 0 {
+		this.mu.Lock()
+		if w.queued {
+			this.queue.remove(w)
+			atomic.AddInt32(&this.waiting, -1)
+			this.mu.Unlock()
+			return stamp
+		}
+		this.mu.Unlock()
+		// signalled meanwhile, pass the wakeup on
+		w.await(nil, time.Time{})
+		this.wake()
+		return stamp
+	}
+	w.await(nil, time.Time{})
+	return 0
+}
+
+// wake signals the first writer or the first run of readers
+func (this *StampedLock) wake() {
+	if atomic.LoadInt32(&this.waiting) == 0 {
+		return
+	}
+	this.mu.Lock()
+	wokeReader := false
+	for w := this.queue.front(); w != nil; w = this.queue.front() {
+		if !w.shared && wokeReader {
+			break
+		}
+		this.queue.remove(w)
+		atomic.AddInt32(&this.waiting, -1)
+		w.signal()
+		if !w.shared {
+			break
+		}
+		wokeReader = true
+	}
+	this.mu.Unlock()
+}
+
+// UnlockWrite releases the write lock, panics with ErrInvalidStamp if stamp
+// doesn't match the state
+func (this *StampedLock) UnlockWrite(stamp uint64) {
+	if this.load() != stamp || stamp&slWBit == 0 {
+		panic(ErrInvalidStamp)
+	}
+	this.store(nextVersion(stamp))
+	this.wake()
+}
+
+// UnlockRead releases a read lock, panics with ErrInvalidStamp if stamp
+// doesn't match the state
+func (this *StampedLock) UnlockRead(stamp uint64) {
+	for {
+		s := this.load()
+		m := s & slABits
+		if s&slSBits != stamp&slSBits || stamp&slABits == 0 || m == 0 || m >= slWBit {
+			panic(ErrInvalidStamp)
+		}
+		if this.cas(s, s-slRUnit) {
+			if m == slRUnit || m == slRFull {
+				this.wake()
+			}
+			return
+		}
+	}
+}
+
+// Unlock releases the mode of stamp, see UnlockWrite and UnlockRead
+func (this *StampedLock) Unlock(stamp uint64) {
+	if stamp&slWBit != 0 {
+		this.UnlockWrite(stamp)
+	} else {
+		this.UnlockRead(stamp)
+	}
+}
+
+// TryOptimisticRead returns a stamp to validate later, 0 if write locked
+func (this *StampedLock) TryOptimisticRead() uint64 {
+	s := this.load()
+	if s&slWBit != 0 {
+		return 0
+	}
+	return s & slSBits
+}
+
+// Validate returns true if the lock hasn't been write locked since stamp
+// was issued, always false for 0
+func (this *StampedLock) Validate(stamp uint64) bool {
+	return stamp&slSBits == this.load()&slSBits
+}
+
+// TryConvertToWriteLock returns a write stamp if stamp is a write stamp,
+// the sole read stamp or a valid optimistic stamp of a free lock, else 0
+func (this *StampedLock) TryConvertToWriteLock(stamp uint64) uint64 {
+	a := stamp & slABits
+	for {
+		s := this.load()
+		if s&slSBits != stamp&slSBits {
+			return 0
+		}
+		switch m := s & slABits; {
+		case m == 0:
+			if a != 0 {
+				return 0
+			}
+			if this.cas(s, s+slWBit) {
+				return s + slWBit
+			}
+		case m == slWBit:
+			if a != m {
+				return 0
+			}
+			return stamp
+		case m == slRUnit && a != 0:
+			if this.cas(s, s-slRUnit+slWBit) {
+				return s - slRUnit + slWBit
+			}
+		default:
+			return 0
+		}
+	}
+}
+
+// TryConvertToReadLock returns a read stamp if stamp is a read stamp, a
+// write stamp, whose lock is downgraded, or a valid optimistic stamp, else 0
+func (this *StampedLock) TryConvertToReadLock(stamp uint64) uint64 {
+	a := stamp & slABits
+	for {
+		s := this.load()
+		if s&slSBits != stamp&slSBits {
+			return 0
+		}
+		switch m := s & slABits; {
+		case a == 0:
+			if m >= slRFull {
+				return 0
+			}
+			if this.cas(s, s+slRUnit) {
+				return s + slRUnit
+			}
+		case m == slWBit:
+			if a != m {
+				return 0
+			}
+			next := nextVersion(s) + slRUnit
+			this.store(next)
+			this.wake()
+			return next
+		case a < slWBit && m != 0:
+			return stamp
+		default:
+			return 0
+		}
+	}
+}
+
+// TryConvertToOptimisticRead releases the lock of stamp and returns an
+// optimistic stamp, or validates an optimistic stamp, else return 0
+func (this *StampedLock) TryConvertToOptimisticRead(stamp uint64) uint64 {
+	a := stamp & slABits
+	for {
+		s := this.load()
+		if s&slSBits != stamp&slSBits {
+			return 0
+		}
+		switch m := s & slABits; {
+		case a == 0:
+			return s & slSBits
+		case m == slWBit:
+			if a != m {
+				return 0
+			}
+			next := nextVersion(s)
+			this.store(next)
+			this.wake()
+			return next
+		case a >= slWBit || m == 0:
+			return 0
+		default:
+			if this.cas(s, s-slRUnit) {
+				if m == slRUnit || m == slRFull {
+					this.wake()
+				}
+				return (s - slRUnit) & slSBits
+			}
+		}
+	}
+}
+
+func (this *StampedLock) IsWriteLocked() bool {
+	return this.load()&slWBit != 0
+}
+
+func (this *StampedLock) IsReadLocked() bool {
+	return this.load()&slRBits != 0
+}
+
+// GetReadLockCount returns the number of read locks held
+func (this *StampedLock) GetReadLockCount() int {
+	return int(this.load() & slRBits)
+}
+
+// IsWriteLockStamp returns true if stamp holds the write lock
+func IsWriteLockStamp(stamp uint64) bool {
+	return stamp&slABits == slWBit
+}
+
+// IsReadLockStamp returns true if stamp holds a read lock
+func IsReadLockStamp(stamp uint64) bool {
+	a := stamp & slABits
+	return a != 0 && a < slWBit
+}
+
+// IsOptimisticReadStamp returns true if stamp is an optimistic read stamp
+func IsOptimisticReadStamp(stamp uint64) bool {
+	return stamp != 0 && stamp&slABits == 0
+}
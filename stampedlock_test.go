@@ -0,0 +1,113 @@
+package guc
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStampedLock(t *testing.T) {
+	var l StampedLock
+	opt := l.TryOptimisticRead()
+	if opt == 0 || !l.Validate(opt) || !IsOptimisticReadStamp(opt) || l.Validate(0) {
+		t.Fatal("optimistic read of a free lock should validate")
+	}
+	r1, r2 := l.ReadLock(), l.TryReadLock()
+	if r1 == 0 || r2 == 0 || !IsReadLockStamp(r1) || l.GetReadLockCount() != 2 || !l.Validate(opt) {
+		t.Fatal("read locks should be shared and keep optimistic stamps valid")
+	}
+	if l.TryWriteLock() != 0 || l.TryConvertToWriteLock(r1) != 0 {
+		t.Fatal("write lock should wait for the readers")
+	}
+	l.UnlockRead(r2)
+	w := l.TryConvertToWriteLock(r1)
+	if w == 0 || !IsWriteLockStamp(w) || !l.IsWriteLocked() || l.IsReadLocked() {
+		t.Fatal("sole reader should convert to writer")
+	}
+	if l.TryOptimisticRead() != 0 || l.TryReadLock() != 0 || l.TryConvertToWriteLock(w) != w {
+		t.Fatal("write lock should exclude readers")
+	}
+	r := func() (result bool) {
+		defer func() {
+			result = recover() == ErrInvalidStamp
+		}()
+		l.UnlockRead(r1)
+		return
+	}()
+	if !r {
+		t.Fatal("unlock of a converted stamp should panic with ErrInvalidStamp")
+	}
+	l.UnlockWrite(w)
+	if l.Validate(opt) || l.IsWriteLocked() {
+		t.Fatal("write unlock should invalidate optimistic stamps")
+	}
+}
+
+func TestStampedLock_Convert(t *testing.T) {
+	l := NewStampedLock()
+	w := l.WriteLock()
+	r := l.TryConvertToReadLock(w)
+	if r == 0 || l.IsWriteLocked() || l.GetReadLockCount() != 1 || l.TryConvertToReadLock(r) != r {
+		t.Fatal("writer should downgrade to reader")
+	}
+	opt := l.TryConvertToOptimisticRead(r)
+	if opt == 0 || l.IsReadLocked() || !l.Validate(opt) {
+		t.Fatal("reader should convert to optimistic read")
+	}
+	r = l.TryConvertToReadLock(opt)
+	if r == 0 || l.GetReadLockCount() != 1 {
+		t.Fatal("valid optimistic stamp should convert to reader")
+	}
+	l.Unlock(r)
+	w = l.TryConvertToWriteLock(opt)
+	if w == 0 {
+		t.Fatal("valid optimistic stamp of a free lock should convert to writer")
+	}
+	opt = l.TryConvertToOptimisticRead(w)
+	if opt == 0 || l.IsWriteLocked() || l.TryConvertToWriteLock(w) != 0 {
+		t.Fatal("writer should convert to optimistic read")
+	}
+	l.Unlock(l.WriteLock())
+	if l.Validate(opt) || l.TryConvertToReadLock(opt) != 0 {
+		t.Fatal("stale stamp should not convert")
+	}
+}
+
+func TestStampedLock_Concurrent(t *testing.T) {
+	var l StampedLock
+	var x, y int64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				switch g % 4 {
+				case 0:
+					w := l.WriteLock()
+					atomic.AddInt64(&x, 1)
+					atomic.AddInt64(&y, 1)
+					l.UnlockWrite(w)
+				case 1:
+					r := l.ReadLock()
+					if atomic.LoadInt64(&x) != atomic.LoadInt64(&y) {
+						t.Error("reader saw a partial write")
+					}
+					l.UnlockRead(r)
+				default:
+					stamp := l.TryOptimisticRead()
+					cx, cy := atomic.LoadInt64(&x), atomic.LoadInt64(&y)
+					if l.Validate(stamp) {
+						if cx != cy {
+							t.Error("validated optimistic read saw a partial write")
+						}
+					}
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if x != 2*2000 || l.IsWriteLocked() || l.IsReadLocked() {
+		t.Fatal("concurrent stamped lock error")
+	}
+}
@@ -0,0 +1,115 @@
+package guc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// waiter states
+const (
+	waiterWaiting int32 = iota
+	waiterSignalled
+	waiterCancelled
+)
+
+// waiter is a goroutine blocked in a synchronizer. it sleeps on its own
+// runtime semaphore, which is released exactly once: either by signal or
+// by cancel, whichever moves the state first
+type waiter struct {
+	sema uint32
+	// Volatile
+	state int32
+	// what the waiter asks the synchronizer for
+	owner  *Owner
+	n      int
+	shared bool
+	// guarded by the lock of the synchronizer
+	prev, next *waiter
+	queued     bool
+}
+
+// signal wakes w, return false if it was cancelled before
+func (w *waiter) signal() bool {
+	if atomic.CompareAndSwapInt32(&w.state, waiterWaiting, waiterSignalled) {
+		SyncRuntimeSemrelease(&w.sema, false)
+		return true
+	}
+	return false
+}
+
+func (w *waiter) cancel() {
+	if atomic.CompareAndSwapInt32(&w.state, waiterWaiting, waiterCancelled) {
+		SyncRuntimeSemrelease(&w.sema, false)
+	}
+}
+
+// await blocks until w is signalled, the deadline passes or ctx is done.
+// the zero deadline and a nil ctx mean no limit, return true if signalled
+func (w *waiter) await(ctx context.Context, deadline time.Time) bool {
+	if !deadline.IsZero() {
+		t := time.AfterFunc(time.Until(deadline), w.cancel)
+		defer t.Stop()
+	}
+	if ctx != nil && ctx.Done() != nil {
+		stop := context.AfterFunc(ctx, w.cancel)
+		defer stop()
+	}
+	SyncRuntimeSemacquire(&w.sema)
+	return atomic.LoadInt32(&w.state) == waiterSignalled
+}
+
+// waitQueue is a FIFO queue of waiters,
+// guarded by the lock of the synchronizer which owns it
+type waitQueue struct {
+	head, tail *waiter
+	size       int
+}
+
+func (q *waitQueue) pushBack(w *waiter) {
+	w.prev, w.next = q.tail, nil
+	if q.tail == nil {
+		q.head = w
+	} else {
+		q.tail.next = w
+	}
+	q.tail = w
+	w.queued = true
+	q.size++
+}
+
+func (q *waitQueue) pushFront(w *waiter) {
+	w.prev, w.next = nil, q.head
+	if q.head == nil {
+		q.tail = w
+	} else {
+		q.head.prev = w
+	}
+	q.head = w
+	w.queued = true
+	q.size++
+}
+
+// remove does nothing if w is not queued
+func (q *waitQueue) remove(w *waiter) {
+	if !w.queued {
+		return
+	}
+	if w.prev == nil {
+		q.head = w.next
+	} else {
+		w.prev.next = w.next
+	}
+	if w.next == nil {
+		q.tail = w.prev
+	} else {
+		w.next.prev = w.prev
+	}
+	w.prev, w.next = nil, nil
+	w.queued = false
+	q.size--
+}
+
+func (q *waitQueue) front() *waiter {
+	return q.head
+}